@@ -2,15 +2,28 @@ package routes
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
 )
 
-// Helper function to send detailed error responses
+// Helper function to send detailed error responses. Rule parse errors also
+// carry the full list of positioned diagnostics for editors to underline,
+// and invalid ASTs the JSON pointer of the bad node.
 func SendErrorResponse(w http.ResponseWriter, statusCode int, message string, err error) {
 	response := map[string]interface{}{
 		"message": message,
 		"error":   err.Error(),
 	}
+	var diagnostics parser.Diagnostics
+	if errors.As(err, &diagnostics) {
+		response["diagnostics"] = diagnostics
+	}
+	var nodeErr *parser.NodeError
+	if errors.As(err, &nodeErr) {
+		response["path"] = nodeErr.Path
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
+)
+
+// maxNameLength limits the length of a rule name.
+const maxNameLength = 100
+
+// namePattern matches rule names: lowercase words of letters and digits
+// joined by hyphens, such as "high-value-customers".
+var namePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// allDigits matches names that could be mistaken for rule ids.
+var allDigits = regexp.MustCompile(`^[0-9]+$`)
+
+// errInvalidParameter marks errors caused by a malformed request parameter.
+var errInvalidParameter = errors.New("invalid parameter")
+
+// ruleMetadataRequest holds the metadata fields accepted when creating or
+// combining rules. Rules are enabled unless "enabled" is false.
+type ruleMetadataRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	OwnerTeam   string   `json:"owner_team"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// metadata validates the request and returns the metadata to store.
+func (req ruleMetadataRequest) metadata() (db.RuleMetadata, error) {
+	if err := validateName(req.Name); err != nil {
+		return db.RuleMetadata{}, err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	return db.RuleMetadata{
+		Name:        req.Name,
+		Description: strings.TrimSpace(req.Description),
+		Tags:        cleanTags(req.Tags),
+		OwnerTeam:   strings.TrimSpace(req.OwnerTeam),
+		Enabled:     enabled,
+	}, nil
+}
+
+// UpdateRuleMetadataHandler changes the name, description, tags, owner team
+// or enabled flag of a rule. Fields left out of the request are unchanged,
+// and an empty name removes the rule's name. No version is added.
+func UpdateRuleMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	var req struct {
+		Name        *string  `json:"name"`
+		Description *string  `json:"description"`
+		Tags        []string `json:"tags"`
+		OwnerTeam   *string  `json:"owner_team"`
+		Enabled     *bool    `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	update := db.MetadataUpdate{
+		Name:      req.Name,
+		OwnerTeam: req.OwnerTeam,
+		Enabled:   req.Enabled,
+	}
+	if req.Name != nil {
+		if err := validateName(*req.Name); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, "Invalid rule metadata", err)
+			return
+		}
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		update.Description = &description
+	}
+	if req.Tags != nil {
+		update.Tags = cleanTags(req.Tags)
+	}
+	if req.OwnerTeam != nil {
+		ownerTeam := strings.TrimSpace(*req.OwnerTeam)
+		update.OwnerTeam = &ownerTeam
+	}
+
+	rule, err := store.UpdateRuleMetadata(id, update)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error updating rule metadata", err)
+		return
+	}
+	ruleCache.Invalidate(id)
+
+	responseData := map[string]interface{}{
+		"rule": rule,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule metadata updated successfully", responseData)
+}
+
+// Helper function to check a rule name; "" means the rule has no name
+func validateName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxNameLength)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("name must be lowercase letters and digits separated by hyphens, got %q", name)
+	}
+	if allDigits.MatchString(name) {
+		return fmt.Errorf("name must not be a number, got %q", name)
+	}
+	return nil
+}
+
+// Helper function to trim tags, dropping empty and repeated ones
+func cleanTags(tags []string) []string {
+	cleaned := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	return cleaned
+}
@@ -2,29 +2,49 @@ package routes
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/optimizer"
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/utils"
 )
 
+// maxEvaluateRequestBytes bounds the size of /evaluate_rule requests.
+const maxEvaluateRequestBytes = 1 << 20
+
 // NewRouter creates a new HTTP router and registers routes.
 func NewRouter() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/create_rule", CreateRuleHandler)
-	mux.HandleFunc("/combine_rules", CombineRulesHandler)
-	mux.HandleFunc("/evaluate_rule", EvaluateRuleHandler)
-	mux.HandleFunc("/ping", PongHandler)
+	mux.HandleFunc("POST /create_rule", CreateRuleHandler)
+	mux.HandleFunc("POST /combine_rules", CombineRulesHandler)
+	mux.HandleFunc("POST /evaluate_rule", EvaluateRuleHandler)
+	mux.HandleFunc("GET /rules", ListRulesHandler)
+	mux.HandleFunc("GET /rules/{id}", GetRuleHandler)
+	mux.HandleFunc("PUT /rules/{id}", UpdateRuleHandler)
+	mux.HandleFunc("PATCH /rules/{id}", UpdateRuleMetadataHandler)
+	mux.HandleFunc("DELETE /rules/{id}", DeleteRuleHandler)
+	mux.HandleFunc("GET /rules/{id}/versions", ListRuleVersionsHandler)
+	mux.HandleFunc("GET /rules/{id}/versions/{version}", GetRuleVersionHandler)
+	mux.HandleFunc("GET /rules/{id}/diff", DiffRuleVersionsHandler)
+	mux.HandleFunc("POST /rules/{id}/rollback", RollbackRuleHandler)
+	mux.HandleFunc("GET /ping", PongHandler)
 	return mux
 }
 
 // CreateRuleHandler handles the creation of a rule and stores it in the database.
+// The rule is optimized first, unless the request sets "optimize" to false.
 func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		RuleString string `json:"rule_string"`
+		Author     string `json:"author"`
+		ChangeNote string `json:"change_note"`
+		Optimize   *bool  `json:"optimize"`
+		ruleMetadataRequest
 	}
 
 	// Decode the incoming request JSON body
@@ -32,6 +52,11 @@ func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
+	metadata, err := req.metadata()
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid rule metadata", err)
+		return
+	}
 
 	// Create AST (Abstract Syntax Tree) from the rule string
 	ast, err := createAST(req.RuleString)
@@ -40,6 +65,14 @@ func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optimize the rule and store it in its canonical form
+	optimized := optimizeAST(ast, req.Optimize)
+	ruleString, ast, err := normalizeRule(optimized.After)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Error normalizing rule", err)
+		return
+	}
+
 	// Convert AST to JSON format to store in the database
 	astJSON, err := json.Marshal(ast)
 	if err != nil {
@@ -47,29 +80,42 @@ func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Insert the rule and the AST into the database
-	query := `INSERT INTO rules (rule_string, ast) VALUES ($1, $2) RETURNING id`
-	var ruleID int
-	err = db.DB.QueryRow(query, req.RuleString, astJSON).Scan(&ruleID)
+	// Insert the rule and the AST into the database as its first version
+	rule, err := store.CreateRule(ruleString, astJSON, metadata, db.Change{Author: req.Author, Note: req.ChangeNote})
 	if err != nil {
-		SendErrorResponse(w, http.StatusInternalServerError, "Error storing rule", err)
+		SendErrorResponse(w, errorStatus(err), "Error storing rule", err)
 		return
 	}
 
 	// Prepare response data
 	responseData := map[string]interface{}{
-		"rule_id": ruleID,
-		"node":    ast,
+		"rule_id":     rule.ID,
+		"name":        rule.Name,
+		"version":     rule.Version,
+		"rule_string": ruleString,
+		"node":        ast,
 	}
+	addOptimization(responseData, optimized)
 
 	// Send success response
 	SendSuccessResponse(w, http.StatusOK, "Rule created successfully", responseData)
 }
 
 // CombineRulesHandler combines multiple rules into one and stores the result in the database.
+// The rules are given as rule strings, stored rule ids or stored rule names,
+// and joined with the "operator" AND, OR or most_frequent, which picks the
+// operator used most often in the rules. It defaults to OR. The combined rule
+// is optimized like a created one.
 func CombineRulesHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Rules []string `json:"rules"`
+		Rules      []string `json:"rules"`
+		RuleIDs    []int    `json:"rule_ids"`
+		RuleNames  []string `json:"rule_names"`
+		Operator   string   `json:"operator"`
+		Author     string   `json:"author"`
+		ChangeNote string   `json:"change_note"`
+		Optimize   *bool    `json:"optimize"`
+		ruleMetadataRequest
 	}
 
 	// Decode the request
@@ -77,11 +123,48 @@ func CombineRulesHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
+	metadata, err := req.metadata()
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid rule metadata", err)
+		return
+	}
+
+	operator := strings.ToUpper(req.Operator)
+	if operator == "" {
+		operator = "OR"
+	}
+	if operator != "AND" && operator != "OR" && operator != mostFrequentOperator {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid operator", fmt.Errorf("operator must be AND, OR or most_frequent, got %q", req.Operator))
+		return
+	}
+
+	// Parse the rule strings and load the stored rules
+	asts, err := parseRules(req.Rules)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Error combining rules", err)
+		return
+	}
+	for _, ref := range ruleRefs(req.RuleIDs, req.RuleNames) {
+		rule, err := loadRuleRef(ref, 0)
+		if err != nil {
+			SendErrorResponse(w, errorStatus(err), "Error loading rule", err)
+			return
+		}
+		asts = append(asts, rule.AST)
+	}
 
 	// Use combineAST to combine rules into a single AST
-	combinedAST, err := combineAST(req.Rules)
+	combinedAST, operator, err := combineAST(operator, asts)
 	if err != nil {
-		SendErrorResponse(w, http.StatusInternalServerError, "Error combining rules", err)
+		SendErrorResponse(w, http.StatusBadRequest, "Error combining rules", err)
+		return
+	}
+
+	// Optimize the combined AST and render the combined rule string from it
+	optimized := optimizeAST(combinedAST, req.Optimize)
+	combinedRuleString, combinedAST, err := normalizeRule(optimized.After)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Error normalizing combined rule", err)
 		return
 	}
 
@@ -92,45 +175,74 @@ func CombineRulesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the combined rule string (concatenating the rules)
-	combinedRuleString := req.Rules[0]
-	for _, rule := range req.Rules[1:] {
-		combinedRuleString += " OR " + rule
-	}
-
 	// Insert the combined rule and the AST into the database
-	query := `INSERT INTO rules (rule_string, ast) VALUES ($1, $2) RETURNING id`
-	var ruleID int
-	err = db.DB.QueryRow(query, combinedRuleString, astJSON).Scan(&ruleID)
+	rule, err := store.CreateRule(combinedRuleString, astJSON, metadata, db.Change{Author: req.Author, Note: req.ChangeNote})
 	if err != nil {
-		SendErrorResponse(w, http.StatusInternalServerError, "Error storing combined rule", err)
+		SendErrorResponse(w, errorStatus(err), "Error storing combined rule", err)
 		return
 	}
 
 	// Prepare response data
 	responseData := map[string]interface{}{
-		"rule_id":       ruleID,
+		"rule_id":       rule.ID,
+		"name":          rule.Name,
+		"version":       rule.Version,
 		"combined_rule": combinedRuleString,
+		"operator":      operator,
 		"node":          combinedAST,
 	}
+	addOptimization(responseData, optimized)
 
 	// Send success response
 	SendSuccessResponse(w, http.StatusOK, "Combined rule created successfully", responseData)
 }
 
-// EvaluateRuleHandler evaluates a rule's AST against provided data.
+// EvaluateRuleHandler evaluates a rule against provided data. The rule is
+// either sent as an AST, or references stored rules by "rule_id" or
+// "rule_name", or by a list of "rule_ids" or "rule_names". Stored rules are
+// evaluated at their current version unless a "version" of a single rule is
+// pinned.
 func EvaluateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		AST  map[string]interface{} `json:"ast"`
-		Data map[string]interface{} `json:"data"`
+		AST       map[string]interface{} `json:"ast"`
+		RuleID    *int                   `json:"rule_id"`
+		RuleName  string                 `json:"rule_name"`
+		Version   int                    `json:"version"`
+		RuleIDs   []int                  `json:"rule_ids"`
+		RuleNames []string               `json:"rule_names"`
+		Data      map[string]interface{} `json:"data"`
 	}
 
-	// Decode the request
+	// Decode the request, refusing oversized bodies
+	r.Body = http.MaxBytesReader(w, r.Body, maxEvaluateRequestBytes)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
 
+	// Evaluate stored rules by id or name
+	var refs []ruleRef
+	single := req.RuleID != nil || req.RuleName != ""
+	if req.RuleID != nil {
+		refs = append(refs, ruleRef{id: *req.RuleID})
+	}
+	if req.RuleName != "" {
+		refs = append(refs, ruleRef{name: req.RuleName})
+	}
+	refs = append(refs, ruleRefs(req.RuleIDs, req.RuleNames)...)
+	if single && len(refs) > 1 {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", fmt.Errorf("rule_id and rule_name reference a single rule and cannot be combined with other rules"))
+		return
+	}
+	if req.Version != 0 && !single {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", fmt.Errorf("version can only be pinned together with rule_id or rule_name"))
+		return
+	}
+	if len(refs) > 0 {
+		evaluateStoredRules(w, refs, single, req.Version, req.Data)
+		return
+	}
+
 	// Convert the incoming AST JSON to your internal AST structure
 	ast, err := utils.ConvertToASTNode(req.AST)
 	if err != nil {
@@ -138,8 +250,24 @@ func EvaluateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject unknown operators and oversized trees before evaluating
+	if err := parser.Validate(ast, parser.DefaultLimits); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid AST", err)
+		return
+	}
+
+	// Type-check function calls before evaluating
+	if err := interpreter.Check(ast); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid AST", locateCheckError(ast, err))
+		return
+	}
+
 	// Evaluate the AST with the provided data
-	result := evaluateAST(ast, req.Data)
+	result, err := evaluateAST(ast, req.Data)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Error evaluating rule", err)
+		return
+	}
 
 	// Prepare response data
 	responseData := map[string]interface{}{
@@ -150,6 +278,86 @@ func EvaluateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	SendSuccessResponse(w, http.StatusOK, "Rule evaluated successfully", responseData)
 }
 
+// ruleRef references a stored rule by id or by name.
+type ruleRef struct {
+	id   int
+	name string
+}
+
+// ruleRefs references stored rules by id, then by name.
+func ruleRefs(ids []int, names []string) []ruleRef {
+	refs := make([]ruleRef, 0, len(ids)+len(names))
+	for _, id := range ids {
+		refs = append(refs, ruleRef{id: id})
+	}
+	for _, name := range names {
+		refs = append(refs, ruleRef{name: name})
+	}
+	return refs
+}
+
+// loadRuleRef loads a referenced rule through the rule cache, at its current
+// version or at the given one.
+func loadRuleRef(ref ruleRef, version int) (*interpreter.StoredRule, error) {
+	switch {
+	case ref.name == "":
+		return ruleCache.GetVersion(ref.id, version)
+	case version == 0:
+		return ruleCache.GetByName(ref.name)
+	}
+
+	// Pinned versions are cached by id
+	id, err := ruleNameToID(ref.name)
+	if err != nil {
+		return nil, err
+	}
+	return ruleCache.GetVersion(id, version)
+}
+
+// evaluateStoredRules evaluates a single stored rule, optionally at a pinned
+// version, or each of a list of stored rules at their current version,
+// loading them through the rule cache. Disabled rules evaluate to false.
+func evaluateStoredRules(w http.ResponseWriter, refs []ruleRef, single bool, version int, data map[string]interface{}) {
+	results := make([]map[string]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		rule, err := loadRuleRef(ref, version)
+		if err != nil {
+			SendErrorResponse(w, errorStatus(err), "Error loading rule", err)
+			return
+		}
+
+		result := false
+		if rule.Enabled {
+			if result, err = evaluateAST(rule.AST, data); err != nil {
+				SendErrorResponse(w, http.StatusBadRequest, "Error evaluating rule", fmt.Errorf("rule %d: %w", rule.ID, err))
+				return
+			}
+		}
+		entry := map[string]interface{}{
+			"rule_id": rule.ID,
+			"result":  result,
+			"enabled": rule.Enabled,
+		}
+		if rule.Name != "" {
+			entry["name"] = rule.Name
+		}
+		results = append(results, entry)
+	}
+
+	if single {
+		if version != 0 {
+			results[0]["version"] = version
+		}
+		SendSuccessResponse(w, http.StatusOK, "Rule evaluated successfully", results[0])
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"results": results,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rules evaluated successfully", responseData)
+}
+
 func PongHandler(w http.ResponseWriter, r *http.Request) {
 	responseData := map[string]interface{}{
 		"result": "Pong",
@@ -161,7 +369,7 @@ func PongHandler(w http.ResponseWriter, r *http.Request) {
 
 // Placeholder functions for AST operations
 
-// createAST creates an AST from a rule string.
+// createAST creates an AST from a rule string and type-checks its function calls.
 func createAST(rule string) (*parser.Node, error) {
 	tokenizer := parser.NewTokenizer(rule)
 	p := parser.NewParser(tokenizer)
@@ -169,33 +377,172 @@ func createAST(rule string) (*parser.Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := interpreter.Check(ast); err != nil {
+		// Point the type error at the offending sub-expression
+		var checkErr *interpreter.CheckError
+		if errors.As(err, &checkErr) {
+			return nil, parser.Diagnostics{
+				parser.NewDiagnostic(rule, checkErr.Node.Start, checkErr.Node.End, parser.CodeTypeError, checkErr.Message, ""),
+			}
+		}
+		return nil, err
+	}
 	return ast, nil
 }
 
-// combineAST combines multiple rule strings into a single AST using the OR operator.
-func combineAST(rules []string) (*parser.Node, error) {
-	if len(rules) == 0 {
-		return nil, fmt.Errorf("no rules provided")
+// mostFrequentOperator asks combineAST for the operator used most often in
+// the rules.
+const mostFrequentOperator = "MOST_FREQUENT"
+
+// parseRules parses each rule string on its own, so diagnostics point into
+// that rule.
+func parseRules(rules []string) ([]*parser.Node, error) {
+	asts := make([]*parser.Node, 0, len(rules))
+	for i, rule := range rules {
+		ast, err := createAST(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		asts = append(asts, ast)
 	}
+	return asts, nil
+}
 
-	// Concatenate rules with OR operator
-	combinedRule := rules[0]
-	for _, rule := range rules[1:] {
-		combinedRule += " OR " + rule
+// combineAST joins ASTs with the AND or OR operator, or with the one used
+// most often in them, keeping identical conditions once. It returns the
+// operator it used.
+func combineAST(operator string, asts []*parser.Node) (*parser.Node, string, error) {
+	if operator == mostFrequentOperator {
+		operator = parser.MostFrequentOperator(asts...)
+	}
+	combined, err := parser.Combine(operator, asts...)
+	if err != nil {
+		return nil, "", err
 	}
+	return combined, operator, nil
+}
 
-	// Create AST from the combined rule
-	ast, err := createAST(combinedRule)
+// optimizeAST optimizes the AST of a rule about to be stored, unless the
+// request turned the optimizer off with "optimize": false.
+func optimizeAST(ast *parser.Node, enabled *bool) *optimizer.Result {
+	if enabled != nil && !*enabled {
+		return &optimizer.Result{Before: ast, After: ast}
+	}
+	return optimizer.Optimize(ast)
+}
+
+// addOptimization adds the rule as written, before optimization, and the
+// rewrites applied to it to a response.
+func addOptimization(responseData map[string]interface{}, result *optimizer.Result) {
+	rewrites := result.Rewrites
+	if rewrites == nil {
+		rewrites = []string{}
+	}
+	responseData["original_rule"] = parser.Format(result.Before)
+	responseData["original_node"] = result.Before
+	responseData["optimizations"] = rewrites
+}
+
+// normalizeRule renders an AST as its canonical rule string and parses that
+// string again, so the returned AST's spans point into the returned string.
+func normalizeRule(ast *parser.Node) (string, *parser.Node, error) {
+	ruleString := parser.Format(ast)
+	normalized, err := createAST(ruleString)
+	if err != nil {
+		return "", nil, err
+	}
+	return ruleString, normalized, nil
+}
+
+// locateCheckError turns a type error in a client supplied AST into a
+// NodeError pointing at the offending node.
+func locateCheckError(ast *parser.Node, err error) error {
+	var checkErr *interpreter.CheckError
+	if !errors.As(err, &checkErr) {
+		return err
+	}
+	path, ok := parser.PathOf(ast, checkErr.Node)
+	if !ok {
+		return err
+	}
+	return &parser.NodeError{Path: path, Message: checkErr.Message}
+}
+
+// store holds the rules served by the handlers.
+var store db.RuleStore
+
+// ruleCache holds the stored rules evaluated by id or name.
+var ruleCache = interpreter.NewRuleCache(loadRule, ruleNameToID)
+
+// SetStore sets the store the handlers read and write rules in, dropping any
+// rules cached from the previous store.
+func SetStore(s db.RuleStore) {
+	store = s
+	ruleCache.Clear()
+}
+
+// loadRule loads a stored rule from the store, at its current version or
+// at the given one. Decoding rebuilds its list sets and patterns, which the
+// cache then keeps.
+func loadRule(id, version int) (*interpreter.StoredRule, error) {
+	rule, err := store.GetRule(id)
 	if err != nil {
 		return nil, err
 	}
 
-	return ast, nil
+	astJSON := rule.AST
+	if version != 0 {
+		ruleVersion, err := store.GetRuleVersion(id, version)
+		if err != nil {
+			return nil, err
+		}
+		astJSON = ruleVersion.AST
+	} else {
+		version = rule.Version
+	}
+
+	ast, err := decodeStoredAST(id, astJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &interpreter.StoredRule{ID: id, Name: rule.Name, Version: version, Enabled: rule.Enabled, AST: ast}, nil
+}
+
+// ruleNameToID looks up the id of a named rule in the store.
+func ruleNameToID(name string) (int, error) {
+	rule, err := store.GetRuleByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return rule.ID, nil
+}
+
+// decodeStoredAST decodes the AST stored for a rule.
+func decodeStoredAST(id int, astJSON []byte) (*parser.Node, error) {
+	var ast parser.Node
+	if err := json.Unmarshal(astJSON, &ast); err != nil {
+		return nil, fmt.Errorf("stored AST of rule %d is invalid: %w", id, err)
+	}
+	return &ast, nil
+}
+
+// errorStatus picks the status code for an error from the database or a
+// request parameter: 404 for unknown rules or versions, 409 for duplicate
+// names, 400 for invalid parameters and 500 for anything else.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrRuleNotFound), errors.Is(err, db.ErrVersionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrDuplicateName):
+		return http.StatusConflict
+	case errors.Is(err, errInvalidParameter):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 // evaluateAST evaluates the given AST node using the provided context.
-func evaluateAST(ast *parser.Node, data map[string]interface{}) bool {
+func evaluateAST(ast *parser.Node, data map[string]interface{}) (bool, error) {
 	context := interpreter.Context(data)
-	result := interpreter.Interpret(ast, context)
-	return result
+	return interpreter.Evaluate(ast, context)
 }
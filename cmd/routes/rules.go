@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Page sizes for GET /rules.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListRulesHandler lists stored rules. Query parameters:
+//
+//	page, page_size                one-based page number and page size
+//	q                              text the rule string must contain
+//	created_after, created_before  RFC 3339 timestamps or YYYY-MM-DD dates
+//	tag                            tag the rule must have
+//	owner_team                     team owning the rule
+//	enabled                        true or false
+//	sort                           id, name, created_at or rule_string; prefix with - to sort descending
+func ListRulesHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	page, err := intParam(params.Get("page"), 1)
+	if err != nil || page < 1 {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid page", fmt.Errorf("page must be a positive integer"))
+		return
+	}
+	pageSize, err := intParam(params.Get("page_size"), defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid page size", fmt.Errorf("page_size must be between 1 and %d", maxPageSize))
+		return
+	}
+
+	query := db.RuleQuery{
+		Search:    params.Get("q"),
+		Tag:       params.Get("tag"),
+		OwnerTeam: params.Get("owner_team"),
+		Limit:     pageSize,
+		Offset:    (page - 1) * pageSize,
+	}
+
+	if query.CreatedAfter, err = timeParam(params.Get("created_after")); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid created_after", err)
+		return
+	}
+	if query.CreatedBefore, err = timeParam(params.Get("created_before")); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid created_before", err)
+		return
+	}
+
+	if enabled := params.Get("enabled"); enabled != "" {
+		value, err := strconv.ParseBool(enabled)
+		if err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, "Invalid enabled", fmt.Errorf("enabled must be true or false, got %q", enabled))
+			return
+		}
+		query.Enabled = &value
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		query.SortBy = strings.TrimPrefix(sort, "-")
+		query.Descending = strings.HasPrefix(sort, "-")
+		if query.SortBy != "id" && query.SortBy != "name" && query.SortBy != "created_at" && query.SortBy != "rule_string" {
+			SendErrorResponse(w, http.StatusBadRequest, "Invalid sort", fmt.Errorf("cannot sort by %q", query.SortBy))
+			return
+		}
+	}
+
+	rules, total, err := store.ListRules(query)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Error listing rules", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"rules":     rules,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rules retrieved successfully", responseData)
+}
+
+// GetRuleHandler returns a single stored rule.
+func GetRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	rule, err := store.GetRule(id)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error retrieving rule", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"rule": rule,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule retrieved successfully", responseData)
+}
+
+// UpdateRuleHandler publishes a new version of a rule, parsing the new rule
+// string and storing its AST. The previous versions stay in the history.
+func UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	var req struct {
+		RuleString string `json:"rule_string"`
+		Author     string `json:"author"`
+		ChangeNote string `json:"change_note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	ast, err := createAST(req.RuleString)
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Error creating AST", err)
+		return
+	}
+	ruleString, ast, err := normalizeRule(ast)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Error normalizing rule", err)
+		return
+	}
+	astJSON, err := json.Marshal(ast)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Error marshaling AST to JSON", err)
+		return
+	}
+
+	rule, err := store.UpdateRule(id, ruleString, astJSON, db.Change{Author: req.Author, Note: req.ChangeNote})
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error updating rule", err)
+		return
+	}
+	ruleCache.Invalidate(id)
+
+	responseData := map[string]interface{}{
+		"rule": rule,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule updated successfully", responseData)
+}
+
+// DeleteRuleHandler deletes a stored rule.
+func DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	if err := store.DeleteRule(id); err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error deleting rule", err)
+		return
+	}
+	ruleCache.Invalidate(id)
+
+	responseData := map[string]interface{}{
+		"rule_id": id,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule deleted successfully", responseData)
+}
+
+// ListRuleVersionsHandler returns the history of a rule, oldest first.
+func ListRuleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	versions, err := store.ListRuleVersions(id)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error retrieving rule versions", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"versions": versions,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule versions retrieved successfully", responseData)
+}
+
+// GetRuleVersionHandler returns one version of a rule.
+func GetRuleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+	version, err := versionParam(r.PathValue("version"))
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid version", err)
+		return
+	}
+
+	ruleVersion, err := store.GetRuleVersion(id, version)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error retrieving rule version", err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"version": ruleVersion,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule version retrieved successfully", responseData)
+}
+
+// DiffRuleVersionsHandler compares two versions of a rule, given by the
+// "from" and "to" query parameters, and lists the subtrees that changed.
+func DiffRuleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+	from, err := versionParam(r.URL.Query().Get("from"))
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid from version", err)
+		return
+	}
+	to, err := versionParam(r.URL.Query().Get("to"))
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid to version", err)
+		return
+	}
+
+	var versions [2]*db.RuleVersion
+	var asts [2]*parser.Node
+	for i, version := range []int{from, to} {
+		if versions[i], err = store.GetRuleVersion(id, version); err != nil {
+			SendErrorResponse(w, errorStatus(err), "Error retrieving rule version", err)
+			return
+		}
+		if asts[i], err = decodeStoredAST(id, versions[i].AST); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Error decoding rule version", err)
+			return
+		}
+	}
+
+	responseData := map[string]interface{}{
+		"from":        versions[0],
+		"to":          versions[1],
+		"differences": parser.Diff(asts[0], asts[1]),
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule versions compared successfully", responseData)
+}
+
+// RollbackRuleHandler publishes an earlier version of a rule again as its
+// new current version.
+func RollbackRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleIDParam(r)
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Invalid rule id", err)
+		return
+	}
+
+	var req struct {
+		Version    int    `json:"version"`
+		Author     string `json:"author"`
+		ChangeNote string `json:"change_note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if req.Version < 1 {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid version", fmt.Errorf("version must be a positive integer"))
+		return
+	}
+
+	rule, err := store.RollbackRule(id, req.Version, db.Change{Author: req.Author, Note: req.ChangeNote})
+	if err != nil {
+		SendErrorResponse(w, errorStatus(err), "Error rolling back rule", err)
+		return
+	}
+	ruleCache.Invalidate(id)
+
+	responseData := map[string]interface{}{
+		"rule": rule,
+	}
+	SendSuccessResponse(w, http.StatusOK, "Rule rolled back successfully", responseData)
+}
+
+// Helper function to read the {id} path parameter, which is either a rule id
+// or a rule name
+func ruleIDParam(r *http.Request) (int, error) {
+	value := r.PathValue("id")
+	if id, err := strconv.Atoi(value); err == nil {
+		if id < 1 {
+			return 0, fmt.Errorf("%w: rule id must be a positive integer, got %q", errInvalidParameter, value)
+		}
+		return id, nil
+	}
+	if validateName(value) != nil {
+		return 0, fmt.Errorf("%w: expected a rule id or name, got %q", errInvalidParameter, value)
+	}
+	return ruleNameToID(value)
+}
+
+// Helper function to read a version number
+func versionParam(value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("version must be a positive integer, got %q", value)
+	}
+	return version, nil
+}
+
+// Helper function to read an optional integer query parameter
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// Helper function to read an optional timestamp query parameter
+func timeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("expected an RFC 3339 timestamp or YYYY-MM-DD date, got %q", value)
+}
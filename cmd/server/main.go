@@ -14,29 +14,35 @@ import (
 )
 
 func main() {
+	// "server migrate ..." migrates the database without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Load environment variables from .env file
 	// err := godotenv.Load()
 	// if err != nil {
 	// 	log.Fatalf("Error loading .env file")
 	// }
 
-	// Initialize the database connection
-	db.InitDB()
-	defer db.DB.Close() // Close the DB connection when the application shuts down
-
-	// Run database migrations
-	err := db.RunMigrations()
+	// Open the rule store selected by RULE_STORE, checking its schema version
+	store, err := db.Open(db.ConfigFromEnv())
 	if err != nil {
-		log.Fatalf("Error running migrations: %v", err)
+		log.Fatalf("Error opening rule store: %v", err)
 	}
+	defer store.Close() // Close the store when the application shuts down
 
 	// Set up the server with routes
+	routes.SetStore(store)
 	router := routes.NewRouter()
 
 	// Enable CORS
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "https://rule-engine-ui.vercel.app"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
 		AllowedHeaders:   []string{"Content-Type"},
 		AllowCredentials: true,
 	})
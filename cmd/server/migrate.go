@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
+)
+
+// migrateUsage describes the migrate subcommand.
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up             apply every pending migration
+  down [steps]   revert the last migration, the last steps migrations, or all of them with "all"
+  version        print the schema version`
+
+// errUsage is returned when the migrate subcommand is misused.
+var errUsage = errors.New(migrateUsage)
+
+// runMigrate runs the migrate subcommand against the store configured by the
+// environment, without starting the HTTP server
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	store, err := db.OpenSQL(db.ConfigFromEnv())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errUsage
+		}
+		if err := store.MigrateUp(); err != nil {
+			return err
+		}
+	case "down":
+		steps, err := downSteps(args[1:])
+		if err != nil {
+			return err
+		}
+		if err := store.MigrateDown(steps); err != nil {
+			return err
+		}
+	case "version":
+		if len(args) != 1 {
+			return errUsage
+		}
+	default:
+		return errUsage
+	}
+
+	return printSchemaVersion(store)
+}
+
+// Helper function to read the steps of "migrate down"; 0 reverts every migration
+func downSteps(args []string) (int, error) {
+	switch {
+	case len(args) == 0:
+		return 1, nil
+	case len(args) > 1:
+		return 0, errUsage
+	case args[0] == "all":
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("steps must be a positive integer or \"all\", got %q", args[0])
+	}
+	return steps, nil
+}
+
+// Helper function to print the schema version and whether it is current
+func printSchemaVersion(store *db.SQLStore) error {
+	version, dirty, err := store.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest, err := store.LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	status := "up to date"
+	switch {
+	case dirty:
+		status = "dirty"
+	case version < latest:
+		status = fmt.Sprintf("%d pending", latest-version)
+	case version > latest:
+		status = "newer than this build"
+	}
+	fmt.Printf("Schema version %d of %d (%s)\n", version, latest, status)
+	return nil
+}
@@ -5,6 +5,7 @@ go 1.23.0
 require (
 	github.com/golang-migrate/migrate v3.5.4+incompatible
 	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.24
 )
 
 require (
@@ -2,91 +2,196 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"strings"
+	"time"
 
-	_ "github.com/golang-migrate/migrate/source/file"
-	_ "github.com/lib/pq"
+	"github.com/golang-migrate/migrate/database"
 )
 
-var DB *sql.DB
+// ErrRuleNotFound is returned when no rule has the requested id.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// Rule is a row of the "rules" table. RuleString and AST are those of the
+// current published Version.
+type Rule struct {
+	ID int `json:"id"`
+	RuleMetadata
+	RuleString string          `json:"rule_string"`
+	AST        json.RawMessage `json:"ast"`
+	Version    int             `json:"version"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
 
-// Initialize the database connection
-func InitDB() {
-	connStr := os.Getenv("DATABASE_URL")
-	if connStr == "" {
-		log.Fatalf("DATABASE_URL is not set")
-	}
+// SQLStore is a RuleStore backed by a SQL database. The SQL that differs
+// between databases comes from its dialect.
+type SQLStore struct {
+	db      *sql.DB
+	dsn     string // data source name the migrations connect with
+	dialect dialect
+}
+
+// dialect holds the SQL and conversions that differ between databases.
+// Queries are written with Postgres' $N placeholders.
+type dialect struct {
+	driverName      string                                      // database/sql driver
+	migrations      string                                      // directory of the embedded migrations
+	migrationDriver func(conn *sql.DB) (database.Driver, error) // golang-migrate driver
+	rebind          func(query string) string                   // rewrites the placeholders for the driver
+	contains        string                                      // matches rule strings containing the argument %s, ignoring case
+	hasTag          string                                      // matches rules tagged with the argument %s
+	tags            func(tags []string) interface{}             // converts a tag list to an argument
+	scanTags        func(tags *[]string) interface{}            // scans a tag list
+	timestamp       func(t time.Time) interface{}               // converts a time to an argument
+	isDuplicate     func(err error) bool                        // reports unique constraint violations
+}
+
+// Close closes the database connection
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// ruleColumns are the columns scanned by scanRule.
+const ruleColumns = "id, COALESCE(name, ''), description, tags, owner_team, enabled, rule_string, ast, version, created_at"
 
-	var err error
-	DB, err = sql.Open("postgres", connStr)
+// Helper function to scan a row of ruleColumns
+func (s *SQLStore) scanRule(row interface{ Scan(...interface{}) error }) (*Rule, error) {
+	var rule Rule
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Description, s.dialect.scanTags(&rule.Tags), &rule.OwnerTeam, &rule.Enabled,
+		&rule.RuleString, &rule.AST, &rule.Version, &rule.CreatedAt)
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return nil, err
 	}
-
-	// Test the database connection
-	if err := DB.Ping(); err != nil {
-		log.Fatalf("Error pinging database: %v", err)
+	if rule.Tags == nil {
+		rule.Tags = []string{}
 	}
-
-	log.Println("Connected to the database successfully!")
+	return &rule, nil
 }
 
-// Run database migrations
-func RunMigrations() error {
-	migrationQuery := `
-	CREATE TABLE IF NOT EXISTS rules (
-		id SERIAL PRIMARY KEY,
-		rule_string TEXT NOT NULL,
-		ast JSONB NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-
-	_, err := DB.Exec(migrationQuery)
+// GetRule retrieves a single rule by id
+func (s *SQLStore) GetRule(id int) (*Rule, error) {
+	rule, err := s.scanRule(s.db.QueryRow(s.dialect.rebind(`SELECT `+ruleColumns+` FROM rules WHERE id = $1`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
 	if err != nil {
-		log.Fatalf("Error running migrations: %v", err)
-		return err
+		return nil, err
 	}
 
-	fmt.Println("Database migrations completed successfully!")
-	return nil
+	return rule, nil
 }
 
-// InsertRule inserts a new rule into the "rules" table in the database.
-func InsertRule(rule string) error {
-	query := `INSERT INTO rules (rule_string) VALUES ($1)`
-	_, err := DB.Exec(query, rule)
-	if err != nil {
-		log.Printf("Error inserting rule: %v", err)
-		return err
-	}
+// RuleQuery filters, sorts and paginates ListRules.
+type RuleQuery struct {
+	Search        string     // case-insensitive substring of rule_string
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	Tag           string
+	OwnerTeam     string
+	Enabled       *bool
+	SortBy        string // "id", "name", "created_at" or "rule_string"
+	Descending    bool
+	Limit         int
+	Offset        int
+}
 
-	log.Printf("Successfully inserted rule: %s", rule)
-	return nil
+// sortColumns whitelists the columns rules can be sorted by.
+var sortColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"created_at":  "created_at",
+	"rule_string": "rule_string",
 }
 
-// GetRules retrieves all rules from the database
-func GetRules() ([]string, error) {
-	rows, err := DB.Query("SELECT rule_string FROM rules")
+// ListRules retrieves one page of rules matching the query, along with the
+// total number of matching rules
+func (s *SQLStore) ListRules(q RuleQuery) ([]Rule, int, error) {
+	var conditions []string
+	var args []interface{}
+	if q.Search != "" {
+		args = append(args, q.Search)
+		conditions = append(conditions, fmt.Sprintf(s.dialect.contains, fmt.Sprintf("$%d", len(args))))
+	}
+	if q.CreatedAfter != nil {
+		args = append(args, s.dialect.timestamp(*q.CreatedAfter))
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if q.CreatedBefore != nil {
+		args = append(args, s.dialect.timestamp(*q.CreatedBefore))
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if q.Tag != "" {
+		args = append(args, q.Tag)
+		conditions = append(conditions, fmt.Sprintf(s.dialect.hasTag, fmt.Sprintf("$%d", len(args))))
+	}
+	if q.OwnerTeam != "" {
+		args = append(args, q.OwnerTeam)
+		conditions = append(conditions, fmt.Sprintf("owner_team = $%d", len(args)))
+	}
+	if q.Enabled != nil {
+		args = append(args, *q.Enabled)
+		conditions = append(conditions, fmt.Sprintf("enabled = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow(s.dialect.rebind("SELECT COUNT(*) FROM rules"+where), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := sortColumns[q.SortBy]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if q.Descending {
+		direction = "DESC"
+	}
+
+	args = append(args, q.Limit, q.Offset)
+	query := fmt.Sprintf("SELECT %s FROM rules%s ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d",
+		ruleColumns, where, column, direction, direction, len(args)-1, len(args))
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var rules []string
+	rules := []Rule{}
 	for rows.Next() {
-		var rule string
-		if err := rows.Scan(&rule); err != nil {
-			return nil, err
+		rule, err := s.scanRule(rows)
+		if err != nil {
+			return nil, 0, err
 		}
-		rules = append(rules, rule)
+		rules = append(rules, *rule)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return rules, nil
+	return rules, total, nil
+}
+
+// DeleteRule deletes a rule by id, along with its versions
+func (s *SQLStore) DeleteRule(id int) error {
+	result, err := s.db.Exec(s.dialect.rebind(`DELETE FROM rules WHERE id = $1`), id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	return nil
 }
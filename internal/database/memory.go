@@ -0,0 +1,316 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a RuleStore that keeps rules in memory, for tests and for
+// running the server without a database. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	nextID   int
+	rules    map[int]*Rule
+	versions map[int][]RuleVersion
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:   1,
+		rules:    make(map[int]*Rule),
+		versions: make(map[int][]RuleVersion),
+	}
+}
+
+// CreateRule inserts a new rule along with its first version
+func (m *MemoryStore) CreateRule(ruleString string, ast []byte, metadata RuleMetadata, change Change) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkName(metadata.Name, 0); err != nil {
+		return nil, err
+	}
+
+	metadata.Tags = copyTags(metadata.tags())
+	rule := &Rule{
+		ID:           m.nextID,
+		RuleMetadata: metadata,
+		RuleString:   ruleString,
+		AST:          copyBytes(ast),
+		Version:      1,
+		CreatedAt:    time.Now().UTC(),
+	}
+	m.nextID++
+	m.rules[rule.ID] = rule
+	m.addVersion(rule, change)
+	return copyRule(rule), nil
+}
+
+// GetRule retrieves a single rule by id
+func (m *MemoryStore) GetRule(id int) (*Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	return copyRule(rule), nil
+}
+
+// GetRuleByName retrieves a single rule by name
+func (m *MemoryStore) GetRuleByName(name string) (*Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rule := range m.rules {
+		if name != "" && rule.Name == name {
+			return copyRule(rule), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+}
+
+// ListRules retrieves one page of rules matching the query, along with the
+// total number of matching rules
+func (m *MemoryStore) ListRules(q RuleQuery) ([]Rule, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matches []*Rule
+	for _, rule := range m.rules {
+		if matchesQuery(rule, q) {
+			matches = append(matches, rule)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if q.Descending {
+			a, b = b, a
+		}
+		if c := compareRules(a, b, q.SortBy); c != 0 {
+			return c < 0
+		}
+		return a.ID < b.ID
+	})
+
+	rules := []Rule{}
+	for i := q.Offset; i < len(matches) && i < q.Offset+q.Limit; i++ {
+		rules = append(rules, *copyRule(matches[i]))
+	}
+	return rules, len(matches), nil
+}
+
+// UpdateRule publishes a new version of a rule
+func (m *MemoryStore) UpdateRule(id int, ruleString string, ast []byte, change Change) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.publishVersion(id, ruleString, ast, change)
+}
+
+// UpdateRuleMetadata changes the metadata of a rule. It does not add a
+// version, since the rule's definition is unchanged.
+func (m *MemoryStore) UpdateRuleMetadata(id int, update MetadataUpdate) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	if update.Name != nil {
+		if err := m.checkName(*update.Name, id); err != nil {
+			return nil, err
+		}
+		rule.Name = *update.Name
+	}
+	if update.Description != nil {
+		rule.Description = *update.Description
+	}
+	if update.Tags != nil {
+		rule.Tags = copyTags(update.Tags)
+	}
+	if update.OwnerTeam != nil {
+		rule.OwnerTeam = *update.OwnerTeam
+	}
+	if update.Enabled != nil {
+		rule.Enabled = *update.Enabled
+	}
+	return copyRule(rule), nil
+}
+
+// RollbackRule publishes a copy of an earlier version of a rule as its new
+// current version, keeping the versions in between in the history
+func (m *MemoryStore) RollbackRule(id, version int, change Change) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, err := m.getVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+	return m.publishVersion(id, previous.RuleString, previous.AST, rollbackChange(change, version))
+}
+
+// ListRuleVersions retrieves the history of a rule, oldest version first
+func (m *MemoryStore) ListRuleVersions(id int) ([]RuleVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.versions[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	copies := make([]RuleVersion, len(versions))
+	for i, v := range versions {
+		copies[i] = v
+		copies[i].AST = copyBytes(v.AST)
+	}
+	return copies, nil
+}
+
+// GetRuleVersion retrieves one version of a rule
+func (m *MemoryStore) GetRuleVersion(id, version int) (*RuleVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getVersion(id, version)
+}
+
+// DeleteRule deletes a rule by id, along with its versions
+func (m *MemoryStore) DeleteRule(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.rules[id]; !ok {
+		return fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	delete(m.rules, id)
+	delete(m.versions, id)
+	return nil
+}
+
+// Close implements RuleStore; there is nothing to release
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// Helper function to reject a name another rule than id already has
+func (m *MemoryStore) checkName(name string, id int) error {
+	if name == "" {
+		return nil
+	}
+	for _, rule := range m.rules {
+		if rule.Name == name && rule.ID != id {
+			return fmt.Errorf("%w: %s", ErrDuplicateName, name)
+		}
+	}
+	return nil
+}
+
+// Helper function to look up a version, returning a copy
+func (m *MemoryStore) getVersion(id, version int) (*RuleVersion, error) {
+	for _, v := range m.versions[id] {
+		if v.Version == version {
+			v.AST = copyBytes(v.AST)
+			return &v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: rule %d version %d", ErrVersionNotFound, id, version)
+}
+
+// Helper function to make a new version current and record it in the history
+func (m *MemoryStore) publishVersion(id int, ruleString string, ast []byte, change Change) (*Rule, error) {
+	rule, ok := m.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	rule.RuleString = ruleString
+	rule.AST = copyBytes(ast)
+	rule.Version++
+	m.addVersion(rule, change)
+	return copyRule(rule), nil
+}
+
+// Helper function to record the current definition of a rule as a version
+func (m *MemoryStore) addVersion(rule *Rule, change Change) {
+	m.versions[rule.ID] = append(m.versions[rule.ID], RuleVersion{
+		RuleID:     rule.ID,
+		Version:    rule.Version,
+		RuleString: rule.RuleString,
+		AST:        copyBytes(rule.AST),
+		Author:     change.Author,
+		ChangeNote: change.Note,
+		CreatedAt:  time.Now().UTC(),
+	})
+}
+
+// Helper function to apply the filters of a query to a rule
+func matchesQuery(rule *Rule, q RuleQuery) bool {
+	if q.Search != "" && !strings.Contains(strings.ToLower(rule.RuleString), strings.ToLower(q.Search)) {
+		return false
+	}
+	if q.CreatedAfter != nil && rule.CreatedAt.Before(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && !rule.CreatedAt.Before(*q.CreatedBefore) {
+		return false
+	}
+	if q.Tag != "" && !hasTag(rule.Tags, q.Tag) {
+		return false
+	}
+	if q.OwnerTeam != "" && rule.OwnerTeam != q.OwnerTeam {
+		return false
+	}
+	if q.Enabled != nil && rule.Enabled != *q.Enabled {
+		return false
+	}
+	return true
+}
+
+// Helper function to compare two rules by a sort column
+func compareRules(a, b *Rule, sortBy string) int {
+	switch sortBy {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "rule_string":
+		return strings.Compare(a.RuleString, b.RuleString)
+	}
+	return 0
+}
+
+// Helper function to check whether a tag list contains a tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to copy a rule, so callers cannot change the stored one
+func copyRule(rule *Rule) *Rule {
+	c := *rule
+	c.Tags = copyTags(rule.Tags)
+	c.AST = copyBytes(rule.AST)
+	return &c
+}
+
+// Helper function to copy a tag list
+func copyTags(tags []string) []string {
+	return append([]string{}, tags...)
+}
+
+// Helper function to copy a byte slice
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
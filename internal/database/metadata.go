@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDuplicateName is returned when another rule already has the name.
+var ErrDuplicateName = errors.New("rule name already in use")
+
+// RuleMetadata describes a rule for the people managing it. Name is a
+// unique slug, or "" for unnamed rules.
+type RuleMetadata struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	OwnerTeam   string   `json:"owner_team"`
+	Enabled     bool     `json:"enabled"`
+}
+
+// Helper function to store an empty tag list rather than NULL
+func (m RuleMetadata) tags() []string {
+	if m.Tags == nil {
+		return []string{}
+	}
+	return m.Tags
+}
+
+// MetadataUpdate changes the metadata fields that are not nil.
+type MetadataUpdate struct {
+	Name        *string
+	Description *string
+	Tags        []string // nil leaves the tags unchanged
+	OwnerTeam   *string
+	Enabled     *bool
+}
+
+// GetRuleByName retrieves a single rule by name
+func (s *SQLStore) GetRuleByName(name string) (*Rule, error) {
+	rule, err := s.scanRule(s.db.QueryRow(s.dialect.rebind(`SELECT `+ruleColumns+` FROM rules WHERE name = $1`), name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// UpdateRuleMetadata changes the metadata of a rule. It does not add a
+// version, since the rule's definition is unchanged.
+func (s *SQLStore) UpdateRuleMetadata(id int, update MetadataUpdate) (*Rule, error) {
+	var assignments []string
+	args := []interface{}{id}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if update.Name != nil {
+		args = append(args, *update.Name)
+		assignments = append(assignments, fmt.Sprintf("name = NULLIF($%d, '')", len(args)))
+	}
+	if update.Description != nil {
+		set("description", *update.Description)
+	}
+	if update.Tags != nil {
+		set("tags", s.dialect.tags(update.Tags))
+	}
+	if update.OwnerTeam != nil {
+		set("owner_team", *update.OwnerTeam)
+	}
+	if update.Enabled != nil {
+		set("enabled", *update.Enabled)
+	}
+	if len(assignments) == 0 {
+		return s.GetRule(id)
+	}
+
+	query := `UPDATE rules SET ` + strings.Join(assignments, ", ") + ` WHERE id = $1 RETURNING ` + ruleColumns
+	rule, err := s.scanRule(s.db.QueryRow(s.dialect.rebind(query), args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	if err != nil {
+		name := ""
+		if update.Name != nil {
+			name = *update.Name
+		}
+		return nil, s.nameError(err, name)
+	}
+
+	return rule, nil
+}
+
+// Helper function to report unique violations on the name column as ErrDuplicateName
+func (s *SQLStore) nameError(err error, name string) error {
+	if s.dialect.isDuplicate(err) {
+		return fmt.Errorf("%w: %s", ErrDuplicateName, name)
+	}
+	return err
+}
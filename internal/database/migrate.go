@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/source"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+)
+
+// migrationFiles holds the migrations of every dialect, one directory each.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrSchemaMismatch is returned when the database schema is not at the
+// version this build expects.
+var ErrSchemaMismatch = errors.New("database schema version mismatch")
+
+// Migrator is implemented by stores with a versioned schema.
+type Migrator interface {
+	// MigrateUp applies every pending migration
+	MigrateUp() error
+	// MigrateDown reverts the given number of migrations, or all of them if
+	// steps is not positive
+	MigrateDown(steps int) error
+	// SchemaVersion returns the version of the last applied migration, 0 if
+	// there is none, and whether it failed halfway
+	SchemaVersion() (version uint, dirty bool, err error)
+	// LatestSchemaVersion returns the version of the last known migration
+	LatestSchemaVersion() (uint, error)
+	// CheckSchema returns ErrSchemaMismatch unless the schema is at the
+	// latest version
+	CheckSchema() error
+}
+
+// MigrateUp applies every pending migration
+func (s *SQLStore) MigrateUp() error {
+	return s.migrate(func(m *migrate.Migrate) error {
+		return m.Up()
+	})
+}
+
+// MigrateDown reverts the given number of migrations, or all of them if
+// steps is not positive
+func (s *SQLStore) MigrateDown(steps int) error {
+	return s.migrate(func(m *migrate.Migrate) error {
+		if steps <= 0 {
+			return m.Down()
+		}
+		// Check first, since golang-migrate reverts what it can before
+		// reporting that too few migrations were applied
+		version, _, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return err
+		}
+		if applied := s.appliedMigrations(version); steps > applied {
+			return fmt.Errorf("cannot revert %d migrations, only %d are applied", steps, applied)
+		}
+		return m.Steps(-steps)
+	})
+}
+
+// SchemaVersion returns the version of the last applied migration, 0 if
+// there is none, and whether it failed halfway
+func (s *SQLStore) SchemaVersion() (version uint, dirty bool, err error) {
+	err = s.migrate(func(m *migrate.Migrate) error {
+		version, dirty, err = m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return nil
+		}
+		return err
+	})
+	return version, dirty, err
+}
+
+// LatestSchemaVersion returns the version of the last embedded migration
+func (s *SQLStore) LatestSchemaVersion() (uint, error) {
+	names, err := s.migrationNames()
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		if m, err := source.DefaultParse(name); err == nil && m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest, nil
+}
+
+// CheckSchema returns ErrSchemaMismatch unless the schema is at the latest
+// version
+func (s *SQLStore) CheckSchema() error {
+	version, dirty, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest, err := s.LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("%w: migration %d failed and must be fixed by hand", ErrSchemaMismatch, version)
+	case version < latest:
+		return fmt.Errorf("%w: schema is at version %d, expected %d; run the pending migrations", ErrSchemaMismatch, version, latest)
+	case version > latest:
+		return fmt.Errorf("%w: schema is at version %d, newer than the expected %d", ErrSchemaMismatch, version, latest)
+	}
+	return nil
+}
+
+// Helper function to list the embedded migrations of the store's dialect
+func (s *SQLStore) migrationNames() ([]string, error) {
+	entries, err := fs.ReadDir(migrationFiles, s.dialect.migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
+
+// Helper function to count the embedded migrations up to a version
+func (s *SQLStore) appliedMigrations(version uint) int {
+	names, _ := s.migrationNames()
+	applied := 0
+	for _, name := range names {
+		if m, err := source.DefaultParse(name); err == nil && m.Direction == source.Up && m.Version <= version {
+			applied++
+		}
+	}
+	return applied
+}
+
+// Helper function to run f with golang-migrate. The migrations use their own
+// connection, since closing the migrate instance closes its database.
+func (s *SQLStore) migrate(f func(m *migrate.Migrate) error) error {
+	names, err := s.migrationNames()
+	if err != nil {
+		return err
+	}
+	src, err := bindata.WithInstance(bindata.Resource(names, func(name string) ([]byte, error) {
+		return migrationFiles.ReadFile(path.Join(s.dialect.migrations, name))
+	}))
+	if err != nil {
+		return err
+	}
+
+	conn, err := sql.Open(s.dialect.driverName, s.dsn)
+	if err != nil {
+		return err
+	}
+	driver, err := s.dialect.migrationDriver(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	m, err := migrate.NewWithInstance("go-bindata", src, s.dialect.driverName, driver)
+	if err != nil {
+		driver.Close()
+		return err
+	}
+	defer m.Close()
+
+	if err := f(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("error running migrations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS rule_versions (
+    rule_id INT NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    rule_string TEXT NOT NULL,
+    ast JSONB NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    change_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rule_id, version)
+);
+
+INSERT INTO rule_versions (rule_id, version, rule_string, ast, created_at)
+SELECT id, version, rule_string, ast, created_at FROM rules
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS rules_tags_idx;
+ALTER TABLE rules DROP COLUMN IF EXISTS enabled;
+ALTER TABLE rules DROP COLUMN IF EXISTS owner_team;
+ALTER TABLE rules DROP COLUMN IF EXISTS tags;
+ALTER TABLE rules DROP COLUMN IF EXISTS description;
+ALTER TABLE rules DROP COLUMN IF EXISTS name;
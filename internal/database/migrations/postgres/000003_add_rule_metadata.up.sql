@@ -0,0 +1,6 @@
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS name TEXT UNIQUE;
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS owner_team TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+CREATE INDEX IF NOT EXISTS rules_tags_idx ON rules USING GIN (tags);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rule_versions;
+ALTER TABLE rules DROP COLUMN version;
@@ -0,0 +1,15 @@
+ALTER TABLE rules ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS rule_versions (
+    rule_id INTEGER NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    rule_string TEXT NOT NULL,
+    ast TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    change_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rule_id, version)
+);
+
+INSERT OR IGNORE INTO rule_versions (rule_id, version, rule_string, ast, created_at)
+SELECT id, version, rule_string, ast, created_at FROM rules;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS rules_name_idx;
+ALTER TABLE rules DROP COLUMN enabled;
+ALTER TABLE rules DROP COLUMN owner_team;
+ALTER TABLE rules DROP COLUMN tags;
+ALTER TABLE rules DROP COLUMN description;
+ALTER TABLE rules DROP COLUMN name;
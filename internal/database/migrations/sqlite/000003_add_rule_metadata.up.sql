@@ -0,0 +1,6 @@
+ALTER TABLE rules ADD COLUMN name TEXT;
+ALTER TABLE rules ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE rules ADD COLUMN owner_team TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE;
+CREATE UNIQUE INDEX IF NOT EXISTS rules_name_idx ON rules (name);
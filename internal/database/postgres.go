@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-migrate/migrate/database"
+	migratepg "github.com/golang-migrate/migrate/database/postgres"
+	"github.com/lib/pq"
+)
+
+// postgres is the dialect of PostgreSQL.
+var postgres = dialect{
+	driverName: "postgres",
+	migrations: "migrations/postgres",
+	migrationDriver: func(conn *sql.DB) (database.Driver, error) {
+		return migratepg.WithInstance(conn, &migratepg.Config{})
+	},
+	rebind:   func(query string) string { return query },
+	contains: "strpos(lower(rule_string), lower(%s)) > 0",
+	hasTag:   "%s = ANY(tags)",
+	tags: func(tags []string) interface{} {
+		return pq.Array(tags)
+	},
+	scanTags: func(tags *[]string) interface{} {
+		return pq.Array(tags)
+	},
+	timestamp: func(t time.Time) interface{} {
+		return t
+	},
+	isDuplicate: func(err error) bool {
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && pqErr.Code == "23505"
+	},
+}
+
+// OpenPostgres connects to a PostgreSQL database
+func OpenPostgres(connStr string) (*SQLStore, error) {
+	conn, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	// Test the database connection
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
+	}
+
+	return &SQLStore{db: conn, dsn: connStr, dialect: postgres}, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/golang-migrate/migrate/database"
+	migratesqlite "github.com/golang-migrate/migrate/database/sqlite3"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqlitePlaceholder matches the $N placeholders rewritten to SQLite's ?N.
+var sqlitePlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// sqliteTimeFormat is the format of SQLite's CURRENT_TIMESTAMP.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// sqlite is the dialect of SQLite. Tags are stored as a JSON array.
+var sqlite = dialect{
+	driverName: "sqlite3",
+	migrations: "migrations/sqlite",
+	migrationDriver: func(conn *sql.DB) (database.Driver, error) {
+		return migratesqlite.WithInstance(conn, &migratesqlite.Config{})
+	},
+	rebind: func(query string) string {
+		return sqlitePlaceholder.ReplaceAllString(query, "?$1")
+	},
+	contains: "instr(lower(rule_string), lower(%s)) > 0",
+	hasTag:   "EXISTS (SELECT 1 FROM json_each(tags) WHERE value = %s)",
+	tags: func(tags []string) interface{} {
+		encoded, _ := json.Marshal(tags)
+		return string(encoded)
+	},
+	scanTags: func(tags *[]string) interface{} {
+		return jsonTags{tags}
+	},
+	timestamp: func(t time.Time) interface{} {
+		return t.UTC().Format(sqliteTimeFormat)
+	},
+	isDuplicate: func(err error) bool {
+		var sqliteErr sqlite3.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	},
+}
+
+// jsonTags scans a tag list stored as a JSON array.
+type jsonTags struct {
+	tags *[]string
+}
+
+// Scan implements sql.Scanner
+func (j jsonTags) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(value), j.tags)
+	case []byte:
+		return json.Unmarshal(value, j.tags)
+	}
+	return fmt.Errorf("cannot scan %T into tags", value)
+}
+
+// OpenSQLite opens an SQLite database file, creating an empty one if needed
+func OpenSQLite(path string) (*SQLStore, error) {
+	dsn := "file:" + path + "?_foreign_keys=1&_busy_timeout=5000"
+	conn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	// SQLite allows a single writer
+	conn.SetMaxOpenConns(1)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	return &SQLStore{db: conn, dsn: dsn, dialect: sqlite}, nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// RuleStore stores rules along with their version history. Every backend
+// returns ErrRuleNotFound, ErrVersionNotFound and ErrDuplicateName, wrapped,
+// for unknown rules, unknown versions and names already in use.
+type RuleStore interface {
+	// CreateRule inserts a new rule along with its first version
+	CreateRule(ruleString string, ast []byte, metadata RuleMetadata, change Change) (*Rule, error)
+	// GetRule retrieves a single rule by id
+	GetRule(id int) (*Rule, error)
+	// GetRuleByName retrieves a single rule by name
+	GetRuleByName(name string) (*Rule, error)
+	// ListRules retrieves one page of rules matching the query, along with
+	// the total number of matching rules
+	ListRules(q RuleQuery) ([]Rule, int, error)
+	// UpdateRule publishes a new version of a rule
+	UpdateRule(id int, ruleString string, ast []byte, change Change) (*Rule, error)
+	// UpdateRuleMetadata changes the metadata of a rule without adding a version
+	UpdateRuleMetadata(id int, update MetadataUpdate) (*Rule, error)
+	// RollbackRule publishes a copy of an earlier version of a rule as its
+	// new current version
+	RollbackRule(id, version int, change Change) (*Rule, error)
+	// ListRuleVersions retrieves the history of a rule, oldest version first
+	ListRuleVersions(id int) ([]RuleVersion, error)
+	// GetRuleVersion retrieves one version of a rule
+	GetRuleVersion(id, version int) (*RuleVersion, error)
+	// DeleteRule deletes a rule and its history
+	DeleteRule(id int) error
+	// Close releases the store's resources
+	Close() error
+}
+
+// Store backends accepted by Open.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Config selects and locates a store backend.
+type Config struct {
+	Driver      string // DriverPostgres, DriverSQLite or DriverMemory
+	URL         string // connection string for Postgres, file path for SQLite
+	AutoMigrate bool   // apply pending migrations when opening the store
+}
+
+// defaultSQLitePath is the database file used when no SQLite path is set.
+const defaultSQLitePath = "rules.db"
+
+// ConfigFromEnv reads the store configuration from the RULE_STORE,
+// DATABASE_URL and AUTO_MIGRATE environment variables. RULE_STORE defaults
+// to Postgres.
+func ConfigFromEnv() Config {
+	config := Config{Driver: os.Getenv("RULE_STORE"), URL: os.Getenv("DATABASE_URL")}
+	if config.Driver == "" {
+		config.Driver = DriverPostgres
+	}
+	if config.Driver == DriverSQLite && config.URL == "" {
+		config.URL = defaultSQLitePath
+	}
+	config.AutoMigrate, _ = strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
+	return config
+}
+
+// Open opens the configured store, applying pending migrations first if
+// AutoMigrate is set. It fails with ErrSchemaMismatch if the schema is not
+// at the latest version.
+func Open(config Config) (RuleStore, error) {
+	if config.Driver == DriverMemory {
+		return NewMemoryStore(), nil
+	}
+
+	store, err := OpenSQL(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.AutoMigrate {
+		err = store.MigrateUp()
+	}
+	if err == nil {
+		err = store.CheckSchema()
+	}
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// OpenSQL opens the configured SQL store without checking its schema, for
+// running migrations
+func OpenSQL(config Config) (*SQLStore, error) {
+	switch config.Driver {
+	case DriverPostgres:
+		if config.URL == "" {
+			return nil, fmt.Errorf("DATABASE_URL is not set")
+		}
+		return OpenPostgres(config.URL)
+	case DriverSQLite:
+		return OpenSQLite(config.URL)
+	case DriverMemory:
+		return nil, fmt.Errorf("the %s store has no schema", DriverMemory)
+	}
+	return nil, fmt.Errorf("unknown rule store %q, expected %s, %s or %s", config.Driver, DriverPostgres, DriverSQLite, DriverMemory)
+}
+
+// Every backend implements RuleStore.
+var (
+	_ RuleStore = (*SQLStore)(nil)
+	_ RuleStore = (*MemoryStore)(nil)
+	_ Migrator  = (*SQLStore)(nil)
+)
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVersionNotFound is returned when a rule has no version with the
+// requested number.
+var ErrVersionNotFound = errors.New("rule version not found")
+
+// Change records who changed a rule and why.
+type Change struct {
+	Author string
+	Note   string
+}
+
+// RuleVersion is a row of the "rule_versions" table. Every create, update
+// and rollback of a rule adds one.
+type RuleVersion struct {
+	RuleID     int             `json:"rule_id"`
+	Version    int             `json:"version"`
+	RuleString string          `json:"rule_string"`
+	AST        json.RawMessage `json:"ast"`
+	Author     string          `json:"author"`
+	ChangeNote string          `json:"change_note"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// versionColumns are the columns scanned by scanVersion.
+const versionColumns = "rule_id, version, rule_string, ast, author, change_note, created_at"
+
+// Helper function to scan a row of versionColumns
+func scanVersion(row interface{ Scan(...interface{}) error }) (*RuleVersion, error) {
+	var v RuleVersion
+	if err := row.Scan(&v.RuleID, &v.Version, &v.RuleString, &v.AST, &v.Author, &v.ChangeNote, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// CreateRule inserts a new rule along with its first version
+func (s *SQLStore) CreateRule(ruleString string, ast []byte, metadata RuleMetadata, change Change) (*Rule, error) {
+	return s.inTransaction(func(tx *sql.Tx) (*Rule, error) {
+		query := `INSERT INTO rules (rule_string, ast, name, description, tags, owner_team, enabled)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7) RETURNING ` + ruleColumns
+		rule, err := s.scanRule(tx.QueryRow(s.dialect.rebind(query), ruleString, ast, metadata.Name, metadata.Description,
+			s.dialect.tags(metadata.tags()), metadata.OwnerTeam, metadata.Enabled))
+		if err != nil {
+			return nil, s.nameError(err, metadata.Name)
+		}
+		return rule, s.insertVersion(tx, rule, change)
+	})
+}
+
+// UpdateRule publishes a new version of a rule
+func (s *SQLStore) UpdateRule(id int, ruleString string, ast []byte, change Change) (*Rule, error) {
+	return s.inTransaction(func(tx *sql.Tx) (*Rule, error) {
+		return s.publishVersion(tx, id, ruleString, ast, change)
+	})
+}
+
+// RollbackRule publishes a copy of an earlier version of a rule as its new
+// current version, keeping the versions in between in the history
+func (s *SQLStore) RollbackRule(id, version int, change Change) (*Rule, error) {
+	return s.inTransaction(func(tx *sql.Tx) (*Rule, error) {
+		previous, err := s.getVersion(tx, id, version)
+		if err != nil {
+			return nil, err
+		}
+		return s.publishVersion(tx, id, previous.RuleString, previous.AST, rollbackChange(change, version))
+	})
+}
+
+// ListRuleVersions retrieves the history of a rule, oldest version first
+func (s *SQLStore) ListRuleVersions(id int) ([]RuleVersion, error) {
+	rows, err := s.db.Query(s.dialect.rebind(`SELECT `+versionColumns+` FROM rule_versions WHERE rule_id = $1 ORDER BY version`), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []RuleVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Every rule has at least its first version
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	return versions, nil
+}
+
+// GetRuleVersion retrieves one version of a rule
+func (s *SQLStore) GetRuleVersion(id, version int) (*RuleVersion, error) {
+	return s.getVersion(s.db, id, version)
+}
+
+// Helper function to look up a version with either the database or a transaction
+func (s *SQLStore) getVersion(q queryer, id, version int) (*RuleVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM rule_versions WHERE rule_id = $1 AND version = $2`
+	v, err := scanVersion(q.QueryRow(s.dialect.rebind(query), id, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: rule %d version %d", ErrVersionNotFound, id, version)
+	}
+	return v, err
+}
+
+// Helper function to make a new version current and record it in the history.
+// Updating the rule row locks it, so concurrent changes get distinct versions.
+func (s *SQLStore) publishVersion(tx *sql.Tx, id int, ruleString string, ast []byte, change Change) (*Rule, error) {
+	query := `UPDATE rules SET rule_string = $2, ast = $3, version = version + 1 WHERE id = $1 RETURNING ` + ruleColumns
+	rule, err := s.scanRule(tx.QueryRow(s.dialect.rebind(query), id, ruleString, ast))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rule, s.insertVersion(tx, rule, change)
+}
+
+// Helper function to record the current definition of a rule as a version
+func (s *SQLStore) insertVersion(tx *sql.Tx, rule *Rule, change Change) error {
+	query := `INSERT INTO rule_versions (rule_id, version, rule_string, ast, author, change_note) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := tx.Exec(s.dialect.rebind(query), rule.ID, rule.Version, rule.RuleString, []byte(rule.AST), change.Author, change.Note)
+	return err
+}
+
+// Helper function to run f in a transaction, committing if it succeeds
+func (s *SQLStore) inTransaction(f func(tx *sql.Tx) (*Rule, error)) (*Rule, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := f(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Helper function to give a rollback its default change note
+func rollbackChange(change Change, version int) Change {
+	if change.Note == "" {
+		change.Note = fmt.Sprintf("Rollback to version %d", version)
+	}
+	return change
+}
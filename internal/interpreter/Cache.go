@@ -0,0 +1,136 @@
+package interpreter
+
+import (
+	"sync"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// StoredRule is a stored rule prepared for evaluation.
+type StoredRule struct {
+	ID      int
+	Name    string
+	Version int
+	Enabled bool
+	AST     *parser.Node
+}
+
+// RuleLoader loads and prepares a stored rule. Version 0 asks for the
+// current version.
+type RuleLoader func(id, version int) (*StoredRule, error)
+
+// NameResolver finds the id of the rule with the given name.
+type NameResolver func(name string) (int, error)
+
+// ruleKey identifies a cached rule version.
+type ruleKey struct {
+	id      int
+	version int
+}
+
+// RuleCache keeps stored rules in memory, with their list sets and patterns
+// already built, so evaluating a rule by id or name does not reload and
+// rebuild it every time. It is safe for concurrent use.
+type RuleCache struct {
+	load    RuleLoader
+	resolve NameResolver
+
+	mu         sync.RWMutex
+	rules      map[ruleKey]*StoredRule
+	names      map[string]int
+	generation uint64
+}
+
+// NewRuleCache creates a cache that loads missing rules with load and looks
+// up unknown names with resolve.
+func NewRuleCache(load RuleLoader, resolve NameResolver) *RuleCache {
+	return &RuleCache{
+		load:    load,
+		resolve: resolve,
+		rules:   make(map[ruleKey]*StoredRule),
+		names:   make(map[string]int),
+	}
+}
+
+// Get returns the current version of the rule, loading it on first use.
+func (c *RuleCache) Get(id int) (*StoredRule, error) {
+	return c.GetVersion(id, 0)
+}
+
+// GetVersion returns a specific version of the rule, loading it on first
+// use. Version 0 is the current version.
+func (c *RuleCache) GetVersion(id, version int) (*StoredRule, error) {
+	key := ruleKey{id, version}
+	c.mu.RLock()
+	rule, ok := c.rules[key]
+	generation := c.generation
+	c.mu.RUnlock()
+	if ok {
+		return rule, nil
+	}
+
+	rule, err := c.load(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only keep the result if no rule was invalidated while it loaded
+	c.mu.Lock()
+	if c.generation == generation {
+		c.rules[key] = rule
+		if rule.Name != "" {
+			c.names[rule.Name] = id
+		}
+	}
+	c.mu.Unlock()
+	return rule, nil
+}
+
+// GetByName returns the current version of the rule with the given name.
+func (c *RuleCache) GetByName(name string) (*StoredRule, error) {
+	c.mu.RLock()
+	id, ok := c.names[name]
+	c.mu.RUnlock()
+
+	if !ok {
+		var err error
+		if id, err = c.resolve(name); err != nil {
+			return nil, err
+		}
+	}
+	return c.Get(id)
+}
+
+// Invalidate drops every cached version of a rule, and its name, after it
+// changed.
+func (c *RuleCache) Invalidate(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.rules {
+		if key.id == id {
+			delete(c.rules, key)
+		}
+	}
+	for name, named := range c.names {
+		if named == id {
+			delete(c.names, name)
+		}
+	}
+	c.generation++
+}
+
+// Clear drops every rule from the cache.
+func (c *RuleCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = make(map[ruleKey]*StoredRule)
+	c.names = make(map[string]int)
+	c.generation++
+}
+
+// Len returns the number of cached rules.
+func (c *RuleCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.rules)
+}
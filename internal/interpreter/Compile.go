@@ -0,0 +1,559 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// CompiledRule is a rule compiled into a tree of Go closures. Literals are
+// converted, and operators, attribute names and functions resolved, once
+// when the rule is compiled instead of on every evaluation. A CompiledRule
+// gives the same results and errors as Evaluate, and is safe for concurrent
+// use.
+type CompiledRule struct {
+	eval condition
+}
+
+// condition evaluates a compiled node as a condition.
+type condition func(context Context) (bool, error)
+
+// expression evaluates a compiled node for its value.
+type expression func(context Context) (interface{}, error)
+
+// comparator compares the values of the two sides of a comparison.
+type comparator func(leftValue, rightValue interface{}) (bool, error)
+
+// Compile compiles the AST of a rule. Functions are looked up in Functions
+// when the rule is compiled, so functions registered later are not seen.
+// Unknown node types and operators and invalid numeric literals, which the
+// parser never produces, are reported here instead of on every evaluation.
+func Compile(node *parser.Node) (*CompiledRule, error) {
+	c := &compiler{}
+	eval := c.compileCondition(node)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &CompiledRule{eval: eval}, nil
+}
+
+// compiler holds the first problem found while compiling a rule.
+type compiler struct {
+	err error
+}
+
+// Helper function to record a problem with the rule, keeping the first one
+func (c *compiler) fail(format string, args ...interface{}) {
+	if c.err == nil {
+		c.err = fmt.Errorf(format, args...)
+	}
+}
+
+// Eval evaluates the rule against the given context data and returns any
+// evaluation error, like Evaluate.
+func (r *CompiledRule) Eval(context Context) (bool, error) {
+	return r.eval(context)
+}
+
+// Helper function to compile a node evaluated as a condition, the
+// counterpart of Evaluate
+func (c *compiler) compileCondition(node *parser.Node) condition {
+	if node == nil {
+		return constantCondition(false)
+	}
+
+	switch node.Type {
+	case parser.LogicalAndExpression:
+		left, right := c.compileCondition(node.Left), c.compileCondition(node.Right)
+		return func(context Context) (bool, error) {
+			result, err := left(context)
+			if err != nil || !result {
+				return false, err
+			}
+			return right(context)
+		}
+	case parser.LogicalOrExpression:
+		left, right := c.compileCondition(node.Left), c.compileCondition(node.Right)
+		return func(context Context) (bool, error) {
+			result, err := left(context)
+			if err != nil || result {
+				return result, err
+			}
+			return right(context)
+		}
+	case parser.UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			value := c.compileExpression(node)
+			return func(context Context) (bool, error) {
+				result, err := value(context)
+				return result != nil, err
+			}
+		}
+		operand := c.compileCondition(node.Left)
+		return func(context Context) (bool, error) {
+			result, err := operand(context)
+			if err != nil {
+				return false, err
+			}
+			return !result, nil
+		}
+	case parser.BinaryExpression:
+		return c.compileBinaryExpression(node)
+	case parser.Identifier, parser.MemberExpression, parser.CallExpression:
+		value := c.compileExpression(node)
+		return func(context Context) (bool, error) {
+			result, err := value(context)
+			if b, ok := result.(bool); ok {
+				return b, err
+			}
+			return result != nil, err
+		}
+	case parser.BooleanLiteral:
+		return constantCondition(node.Value == "true")
+	case parser.NullLiteral:
+		return constantCondition(false)
+	case parser.NumericLiteral, parser.StringLiteral:
+		key := node.Value
+		return func(context Context) (bool, error) {
+			return context[key] != nil, nil
+		}
+	}
+
+	c.fail("unknown node type: %s", node.Type)
+	return constantCondition(false)
+}
+
+// Helper function to compile a comparison, the counterpart of
+// evaluateBinaryExpression
+func (c *compiler) compileBinaryExpression(node *parser.Node) condition {
+	if node.Value == "IN" || node.Value == "NOT IN" {
+		return c.compileMembershipExpression(node)
+	}
+
+	left, right := c.compileExpression(node.Left), c.compileExpression(node.Right)
+	operator := node.Value
+
+	if isNullLiteral(node.Left) || isNullLiteral(node.Right) {
+		// Null checks: a missing attribute or JSON null equals null
+		test := func(leftValue, rightValue interface{}) bool { return false }
+		switch operator {
+		case "=":
+			test = func(leftValue, rightValue interface{}) bool { return leftValue == nil && rightValue == nil }
+		case "!=", "<>":
+			test = func(leftValue, rightValue interface{}) bool { return leftValue != nil || rightValue != nil }
+		}
+		return func(context Context) (bool, error) {
+			leftValue, err := left(context)
+			if err != nil {
+				return false, err
+			}
+			rightValue, err := right(context)
+			if err != nil {
+				return false, err
+			}
+			return test(leftValue, rightValue), nil
+		}
+	}
+
+	compare := c.compileComparator(node)
+	return func(context Context) (bool, error) {
+		leftValue, err := left(context)
+		if err != nil {
+			return false, err
+		}
+		rightValue, err := right(context)
+		if err != nil {
+			return false, err
+		}
+
+		if leftValue == nil || rightValue == nil {
+			return false, nil
+		}
+		if isTemporal(leftValue) || isTemporal(rightValue) {
+			return evaluateTemporalComparison(operator, leftValue, rightValue), nil
+		}
+		return compare(leftValue, rightValue)
+	}
+}
+
+// Helper function to pick the comparison of two present, non-temporal values
+// for an operator. Unlike the interpreter, = and != do not print values of
+// mismatched types, which are simply not equal.
+func (c *compiler) compileComparator(node *parser.Node) comparator {
+	switch node.Value {
+	case "=":
+		return func(leftValue, rightValue interface{}) (bool, error) {
+			// Values of mismatched types are not equal
+			equal, err := compareWithSameType(leftValue, rightValue)
+			return err == nil && equal, nil
+		}
+	case "!=", "<>":
+		return func(leftValue, rightValue interface{}) (bool, error) {
+			equal, err := compareWithSameType(leftValue, rightValue)
+			return err == nil && !equal, nil
+		}
+	case ">":
+		return compareNumbers(func(left, right float64) bool { return left > right })
+	case "<":
+		return compareNumbers(func(left, right float64) bool { return left < right })
+	case ">=":
+		return compareNumbers(func(left, right float64) bool { return left >= right })
+	case "<=":
+		return compareNumbers(func(left, right float64) bool { return left <= right })
+	case "CONTAINS", "STARTS_WITH", "ENDS_WITH", "LIKE", "MATCHES":
+		return func(leftValue, rightValue interface{}) (bool, error) {
+			return evaluateStringExpression(node, leftValue, rightValue)
+		}
+	}
+
+	c.fail("unknown comparison operator: %s", node.Value)
+	return func(leftValue, rightValue interface{}) (bool, error) {
+		return false, nil
+	}
+}
+
+// Helper function to compare two values as numbers. Values that are not
+// numbers never match.
+func compareNumbers(test func(left, right float64) bool) comparator {
+	return func(leftValue, rightValue interface{}) (bool, error) {
+		leftNum, leftOk := toNumber(leftValue)
+		rightNum, rightOk := toNumber(rightValue)
+		return leftOk && rightOk && test(leftNum, rightNum), nil
+	}
+}
+
+// Helper function to compile IN / NOT IN, the counterpart of
+// evaluateMembershipExpression and containsValue
+func (c *compiler) compileMembershipExpression(node *parser.Node) condition {
+	left := c.compileExpression(node.Left)
+	contains := c.compileContains(node.Right)
+	negate := node.Value == "NOT IN"
+	null := !negate && holdsNull(node.Right)
+
+	return func(context Context) (bool, error) {
+		value, err := left(context)
+		if err != nil {
+			return false, err
+		}
+		if value == nil {
+			return null, nil
+		}
+
+		found, present, err := contains(context, value)
+		if err != nil || !present {
+			return false, err
+		}
+		return found != negate, nil
+	}
+}
+
+// Helper function to compile the list of an IN. List literals use their hash
+// set and only evaluate their non-constant elements.
+func (c *compiler) compileContains(listNode *parser.Node) func(context Context, value interface{}) (found, present bool, err error) {
+	if listNode.Type == parser.ListLiteral && listNode.Set != nil {
+		set := listNode.Set
+		dynamic := c.compileExpressions(set.Dynamic)
+		return func(context Context, value interface{}) (bool, bool, error) {
+			key := normalizeNumber(value)
+			if i, ok := key.(int); ok {
+				key = float64(i)
+			}
+			if set.Contains(key) {
+				return true, true, nil
+			}
+
+			// Every element is evaluated, so an error is reported even after a match
+			found := false
+			for _, element := range dynamic {
+				candidate, err := element(context)
+				if err != nil {
+					return false, false, err
+				}
+				found = found || matches(value, candidate)
+			}
+			return found, true, nil
+		}
+	}
+
+	list := c.compileExpression(listNode)
+	return func(context Context, value interface{}) (bool, bool, error) {
+		listValue, err := list(context)
+		if err != nil || listValue == nil {
+			return false, false, err
+		}
+
+		candidates, ok := toList(listValue)
+		if !ok {
+			return false, false, fmt.Errorf("right side of IN must be a list, got '%v' of type %T", listValue, listValue)
+		}
+		for _, candidate := range candidates {
+			if matches(value, candidate) {
+				return true, true, nil
+			}
+		}
+		return false, true, nil
+	}
+}
+
+// Helper function to test whether a list element equals a value
+func matches(value, candidate interface{}) bool {
+	if candidate == nil {
+		return false
+	}
+	equal, err := compareWithSameType(value, candidate)
+	return err == nil && equal
+}
+
+// Helper function to compile a node evaluated for its value, the counterpart
+// of evaluateExpression
+func (c *compiler) compileExpression(node *parser.Node) expression {
+	if node == nil {
+		return constantExpression(nil)
+	}
+
+	switch node.Type {
+	case parser.Identifier:
+		name := node.Value
+		return func(context Context) (interface{}, error) {
+			return context[name], nil
+		}
+	case parser.NumericLiteral:
+		num, ok := node.LiteralValue()
+		if !ok {
+			c.fail("invalid numeric literal: '%s'", node.Value)
+		}
+		return constantExpression(num)
+	case parser.StringLiteral, parser.BooleanLiteral, parser.NullLiteral:
+		value, _ := node.LiteralValue()
+		return constantExpression(value)
+	case parser.DurationLiteral:
+		duration, ok := node.LiteralValue()
+		if !ok {
+			err := fmt.Errorf("invalid duration literal: '%s'", node.Value)
+			return func(context Context) (interface{}, error) {
+				return nil, err
+			}
+		}
+		return constantExpression(duration)
+	case parser.ListLiteral:
+		elements := c.compileExpressions(node.Elements)
+		return func(context Context) (interface{}, error) {
+			list := make([]interface{}, 0, len(elements))
+			for _, element := range elements {
+				value, err := element(context)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			return list, nil
+		}
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		return c.compileArithmeticExpression(node)
+	case parser.UnaryExpression:
+		return c.compileSignExpression(node)
+	case parser.MemberExpression:
+		return c.compileMemberExpression(node)
+	case parser.CallExpression:
+		return c.compileCallExpression(node)
+	}
+
+	return constantExpression(nil)
+}
+
+// Helper function to compile a list of nodes for their values
+func (c *compiler) compileExpressions(nodes []*parser.Node) []expression {
+	compiled := make([]expression, len(nodes))
+	for i, node := range nodes {
+		compiled[i] = c.compileExpression(node)
+	}
+	return compiled
+}
+
+// Helper function to compile arithmetic, the counterpart of
+// evaluateArithmeticExpression
+func (c *compiler) compileArithmeticExpression(node *parser.Node) expression {
+	left, right := c.compileExpression(node.Left), c.compileExpression(node.Right)
+	operator := node.Value
+
+	var apply func(leftNum, rightNum float64, leftValue, rightValue interface{}) (interface{}, error)
+	switch operator {
+	case "+":
+		apply = func(leftNum, rightNum float64, _, _ interface{}) (interface{}, error) { return leftNum + rightNum, nil }
+	case "-":
+		apply = func(leftNum, rightNum float64, _, _ interface{}) (interface{}, error) { return leftNum - rightNum, nil }
+	case "*":
+		apply = func(leftNum, rightNum float64, _, _ interface{}) (interface{}, error) { return leftNum * rightNum, nil }
+	case "/":
+		apply = func(leftNum, rightNum float64, leftValue, rightValue interface{}) (interface{}, error) {
+			if rightNum == 0 {
+				return nil, fmt.Errorf("division by zero: %v / %v", leftValue, rightValue)
+			}
+			return leftNum / rightNum, nil
+		}
+	case "%":
+		apply = func(leftNum, rightNum float64, leftValue, rightValue interface{}) (interface{}, error) {
+			if rightNum == 0 {
+				return nil, fmt.Errorf("modulo by zero: %v %% %v", leftValue, rightValue)
+			}
+			return math.Mod(leftNum, rightNum), nil
+		}
+	default:
+		c.fail("unknown arithmetic operator: %s", operator)
+		apply = func(float64, float64, interface{}, interface{}) (interface{}, error) {
+			return nil, nil
+		}
+	}
+
+	return func(context Context) (interface{}, error) {
+		leftValue, err := left(context)
+		if err != nil {
+			return nil, err
+		}
+		rightValue, err := right(context)
+		if err != nil {
+			return nil, err
+		}
+
+		if leftValue == nil || rightValue == nil {
+			return nil, nil
+		}
+		if isTemporal(leftValue) || isTemporal(rightValue) {
+			return evaluateTemporalArithmetic(operator, leftValue, rightValue)
+		}
+
+		leftNum, ok := toNumber(leftValue)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, leftValue, leftValue)
+		}
+		rightNum, ok := toNumber(rightValue)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, rightValue, rightValue)
+		}
+		return apply(leftNum, rightNum, leftValue, rightValue)
+	}
+}
+
+// Helper function to compile an arithmetic sign, the counterpart of
+// evaluateSignExpression
+func (c *compiler) compileSignExpression(node *parser.Node) expression {
+	operand := c.compileExpression(node.Left)
+	operator := node.Value
+
+	return func(context Context) (interface{}, error) {
+		value, err := operand(context)
+		if err != nil || value == nil {
+			return nil, err
+		}
+
+		if duration, ok := value.(time.Duration); ok {
+			if operator == "-" {
+				return -duration, nil
+			}
+			return duration, nil
+		}
+
+		num, ok := toNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, value, value)
+		}
+
+		switch operator {
+		case "-":
+			return -num, nil
+		case "+":
+			return num, nil
+		}
+		return nil, fmt.Errorf("unknown unary operator in expression: %s", operator)
+	}
+}
+
+// Helper function to compile one step of an attribute path, the counterpart
+// of evaluateMemberExpression
+func (c *compiler) compileMemberExpression(node *parser.Node) expression {
+	object := c.compileExpression(node.Left)
+
+	if node.Value == "." {
+		var key interface{} = node.Right.Value
+		return func(context Context) (interface{}, error) {
+			value, err := object(context)
+			if err != nil || value == nil {
+				return nil, err
+			}
+			return lookupMember(value, key)
+		}
+	}
+
+	index := c.compileExpression(node.Right)
+	return func(context Context) (interface{}, error) {
+		value, err := object(context)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		key, err := index(context)
+		if err != nil || key == nil {
+			return nil, err
+		}
+		return lookupMember(value, key)
+	}
+}
+
+// Helper function to compile a function call, the counterpart of
+// evaluateCallExpression. The function is looked up once.
+func (c *compiler) compileCallExpression(node *parser.Node) expression {
+	fn, ok := Functions.Lookup(node.Value)
+	if !ok {
+		err := fmt.Errorf("unknown function: %s", node.Value)
+		return func(context Context) (interface{}, error) {
+			return nil, err
+		}
+	}
+	if err := fn.checkArity(len(node.Arguments)); err != nil {
+		return func(context Context) (interface{}, error) {
+			return nil, err
+		}
+	}
+
+	arguments := c.compileExpressions(node.Arguments)
+	params := make([]Type, len(arguments))
+	for i := range params {
+		params[i] = fn.paramType(i)
+	}
+
+	return func(context Context) (interface{}, error) {
+		args := make([]interface{}, len(arguments))
+		for i, argument := range arguments {
+			value, err := argument(context)
+			if err != nil || value == nil {
+				return nil, err
+			}
+
+			args[i], err = convertArgument(value, params[i])
+			if err != nil {
+				return nil, fmt.Errorf("argument %d of %s: %w", i+1, fn.Name, err)
+			}
+		}
+		return fn.Call(args)
+	}
+}
+
+// Helper function to compile a condition with a fixed result
+func constantCondition(result bool) condition {
+	return func(context Context) (bool, error) {
+		return result, nil
+	}
+}
+
+// Helper function to compile an expression with a fixed value
+func constantExpression(value interface{}) expression {
+	return func(context Context) (interface{}, error) {
+		return value, nil
+	}
+}
+
+// Helper function to test for a null literal
+func isNullLiteral(node *parser.Node) bool {
+	return node != nil && node.Type == parser.NullLiteral
+}
@@ -0,0 +1,486 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Type is the type of a value passed to or returned from a rule function.
+type Type int
+
+const (
+	TypeAny Type = iota
+	TypeNumber
+	TypeString
+	TypeBool
+	TypeList
+	TypeTime
+	TypeDuration
+)
+
+// String returns the name of the type as shown in error messages.
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeList:
+		return "list"
+	case TypeTime:
+		return "time"
+	case TypeDuration:
+		return "duration"
+	default:
+		return "any"
+	}
+}
+
+// Function is a Go function callable from rules, e.g. lower(name).
+// Arguments are converted to the declared Params types before Call runs:
+// numbers are float64, lists are []interface{}, times are time.Time and
+// durations are time.Duration.
+// If Variadic is set, the last parameter may be repeated any number of times
+// (including zero).
+type Function struct {
+	Name     string
+	Params   []Type
+	Variadic bool
+	Returns  Type
+	Call     func(args []interface{}) (interface{}, error)
+}
+
+// FunctionRegistry holds the functions available to rules.
+type FunctionRegistry struct {
+	mu        sync.RWMutex
+	functions map[string]*Function
+}
+
+var functionName = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// NewFunctionRegistry creates an empty FunctionRegistry.
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{
+		functions: make(map[string]*Function),
+	}
+}
+
+// Functions is the registry used by Interpret, Evaluate and Check. It starts
+// out with the standard library and Go code may register more functions.
+var Functions = NewStandardFunctionRegistry()
+
+// Register adds a function to the registry.
+func (r *FunctionRegistry) Register(fn Function) error {
+	if !functionName.MatchString(fn.Name) {
+		return fmt.Errorf("invalid function name: '%s'", fn.Name)
+	}
+	if fn.Call == nil {
+		return fmt.Errorf("function %s has no implementation", fn.Name)
+	}
+	if fn.Variadic && len(fn.Params) == 0 {
+		return fmt.Errorf("variadic function %s must declare at least one parameter", fn.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.functions[fn.Name]; exists {
+		return fmt.Errorf("function %s is already registered", fn.Name)
+	}
+	r.functions[fn.Name] = &fn
+	return nil
+}
+
+// MustRegister adds a function to the registry and panics if it is invalid.
+func (r *FunctionRegistry) MustRegister(fn Function) {
+	if err := r.Register(fn); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the function registered under name.
+func (r *FunctionRegistry) Lookup(name string) (*Function, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.functions[name]
+	return fn, ok
+}
+
+// Names returns the sorted names of all registered functions.
+func (r *FunctionRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.functions))
+	for name := range r.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paramType returns the declared type of the i-th argument.
+func (fn *Function) paramType(i int) Type {
+	if i >= len(fn.Params) {
+		return fn.Params[len(fn.Params)-1]
+	}
+	return fn.Params[i]
+}
+
+// checkArity verifies the number of arguments passed to the function.
+func (fn *Function) checkArity(count int) error {
+	if fn.Variadic {
+		if count < len(fn.Params)-1 {
+			return fmt.Errorf("function %s expects at least %d arguments, got %d", fn.Name, len(fn.Params)-1, count)
+		}
+		return nil
+	}
+
+	if count != len(fn.Params) {
+		return fmt.Errorf("function %s expects %d arguments, got %d", fn.Name, len(fn.Params), count)
+	}
+	return nil
+}
+
+// CheckError is a type error found by Check. Node is the call or argument
+// at fault, so its span can be highlighted in the rule string.
+type CheckError struct {
+	Node    *parser.Node
+	Message string
+}
+
+// Error returns the message of the type error.
+func (e *CheckError) Error() string {
+	return e.Message
+}
+
+// Check verifies that every function call in the AST refers to a registered
+// function, with the right number of arguments, and that arguments whose
+// type is known before evaluation (literals, arithmetic, other calls) match
+// the declared parameter types. It is run when a rule is created.
+func Check(node *parser.Node) error {
+	return Functions.Check(node)
+}
+
+// Check type-checks the function calls in the AST against this registry.
+func (r *FunctionRegistry) Check(node *parser.Node) error {
+	_, err := r.staticType(node)
+	return err
+}
+
+// staticType checks the node and returns the type it is known to produce.
+func (r *FunctionRegistry) staticType(node *parser.Node) (Type, error) {
+	if node == nil {
+		return TypeAny, nil
+	}
+
+	left, err := r.staticType(node.Left)
+	if err != nil {
+		return TypeAny, err
+	}
+	right, err := r.staticType(node.Right)
+	if err != nil {
+		return TypeAny, err
+	}
+	for _, element := range node.Elements {
+		if _, err := r.staticType(element); err != nil {
+			return TypeAny, err
+		}
+	}
+
+	switch node.Type {
+	case parser.NumericLiteral:
+		return TypeNumber, nil
+	case parser.StringLiteral:
+		return TypeString, nil
+	case parser.BooleanLiteral:
+		return TypeBool, nil
+	case parser.DurationLiteral:
+		return TypeDuration, nil
+	case parser.ListLiteral:
+		return TypeList, nil
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		// Arithmetic on times and durations has several result types
+		if left == TypeNumber && right == TypeNumber {
+			return TypeNumber, nil
+		}
+		return TypeAny, nil
+	case parser.LogicalAndExpression, parser.LogicalOrExpression, parser.BinaryExpression:
+		return TypeBool, nil
+	case parser.UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			return left, nil
+		}
+		return TypeBool, nil
+	case parser.CallExpression:
+		return r.checkCall(node)
+	}
+
+	return TypeAny, nil
+}
+
+// checkCall type-checks a single function call.
+func (r *FunctionRegistry) checkCall(node *parser.Node) (Type, error) {
+	fn, ok := r.Lookup(node.Value)
+	if !ok {
+		return TypeAny, &CheckError{Node: node, Message: fmt.Sprintf("unknown function: %s", node.Value)}
+	}
+	if err := fn.checkArity(len(node.Arguments)); err != nil {
+		return TypeAny, &CheckError{Node: node, Message: err.Error()}
+	}
+
+	for i, argument := range node.Arguments {
+		argType, err := r.staticType(argument)
+		if err != nil {
+			return TypeAny, err
+		}
+
+		want := fn.paramType(i)
+		if want != TypeAny && argType != TypeAny && argType != want {
+			return TypeAny, &CheckError{
+				Node:    argument,
+				Message: fmt.Sprintf("argument %d of %s must be a %s, got a %s", i+1, fn.Name, want, argType),
+			}
+		}
+	}
+
+	return fn.Returns, nil
+}
+
+// Helper function to evaluate a function call. A missing argument makes the
+// result missing, like arithmetic on a missing attribute.
+func evaluateCallExpression(node *parser.Node, context Context) (interface{}, error) {
+	fn, ok := Functions.Lookup(node.Value)
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", node.Value)
+	}
+	if err := fn.checkArity(len(node.Arguments)); err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, len(node.Arguments))
+	for i, argument := range node.Arguments {
+		value, err := evaluateExpression(argument, context)
+		if err != nil || value == nil {
+			return nil, err
+		}
+
+		args[i], err = convertArgument(value, fn.paramType(i))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i+1, fn.Name, err)
+		}
+	}
+
+	return fn.Call(args)
+}
+
+// Helper function to convert an argument to the declared parameter type
+func convertArgument(value interface{}, want Type) (interface{}, error) {
+	switch want {
+	case TypeNumber:
+		if num, ok := toNumber(value); ok {
+			return num, nil
+		}
+	case TypeString:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+	case TypeBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case TypeList:
+		if list, ok := toList(value); ok {
+			return list, nil
+		}
+	case TypeTime:
+		if t, ok := toTime(value); ok {
+			return t, nil
+		}
+	case TypeDuration:
+		if d, ok := toDuration(value); ok {
+			return d, nil
+		}
+	default:
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("expected a %s, got '%v' of type %T", want, value, value)
+}
+
+// NewStandardFunctionRegistry creates a registry holding the standard
+// library of string, math and collection functions.
+func NewStandardFunctionRegistry() *FunctionRegistry {
+	r := NewFunctionRegistry()
+
+	// String functions
+	r.MustRegister(Function{Name: "lower", Params: []Type{TypeString}, Returns: TypeString,
+		Call: func(args []interface{}) (interface{}, error) { return strings.ToLower(args[0].(string)), nil }})
+	r.MustRegister(Function{Name: "upper", Params: []Type{TypeString}, Returns: TypeString,
+		Call: func(args []interface{}) (interface{}, error) { return strings.ToUpper(args[0].(string)), nil }})
+	r.MustRegister(Function{Name: "trim", Params: []Type{TypeString}, Returns: TypeString,
+		Call: func(args []interface{}) (interface{}, error) { return strings.TrimSpace(args[0].(string)), nil }})
+	r.MustRegister(Function{Name: "replace", Params: []Type{TypeString, TypeString, TypeString}, Returns: TypeString,
+		Call: func(args []interface{}) (interface{}, error) {
+			return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
+		}})
+	r.MustRegister(Function{Name: "substr", Params: []Type{TypeString, TypeNumber, TypeNumber}, Returns: TypeString,
+		Call: substr})
+	r.MustRegister(Function{Name: "split", Params: []Type{TypeString, TypeString}, Returns: TypeList,
+		Call: func(args []interface{}) (interface{}, error) {
+			parts := strings.Split(args[0].(string), args[1].(string))
+			list := make([]interface{}, len(parts))
+			for i, part := range parts {
+				list[i] = part
+			}
+			return list, nil
+		}})
+	r.MustRegister(Function{Name: "concat", Params: []Type{TypeAny}, Variadic: true, Returns: TypeString,
+		Call: func(args []interface{}) (interface{}, error) {
+			var builder strings.Builder
+			for _, arg := range args {
+				fmt.Fprint(&builder, arg)
+			}
+			return builder.String(), nil
+		}})
+
+	// Math functions
+	r.MustRegister(Function{Name: "abs", Params: []Type{TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return math.Abs(args[0].(float64)), nil }})
+	r.MustRegister(Function{Name: "round", Params: []Type{TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return math.Round(args[0].(float64)), nil }})
+	r.MustRegister(Function{Name: "floor", Params: []Type{TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return math.Floor(args[0].(float64)), nil }})
+	r.MustRegister(Function{Name: "ceil", Params: []Type{TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return math.Ceil(args[0].(float64)), nil }})
+	r.MustRegister(Function{Name: "sqrt", Params: []Type{TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) {
+			if args[0].(float64) < 0 {
+				return nil, fmt.Errorf("sqrt of negative number %v", args[0])
+			}
+			return math.Sqrt(args[0].(float64)), nil
+		}})
+	r.MustRegister(Function{Name: "pow", Params: []Type{TypeNumber, TypeNumber}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) {
+			return math.Pow(args[0].(float64), args[1].(float64)), nil
+		}})
+	r.MustRegister(Function{Name: "min", Params: []Type{TypeNumber, TypeNumber}, Variadic: true, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return reduceNumbers(args, math.Min), nil }})
+	r.MustRegister(Function{Name: "max", Params: []Type{TypeNumber, TypeNumber}, Variadic: true, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) { return reduceNumbers(args, math.Max), nil }})
+
+	// Collection functions
+	r.MustRegister(Function{Name: "len", Params: []Type{TypeAny}, Returns: TypeNumber, Call: length})
+	r.MustRegister(Function{Name: "sum", Params: []Type{TypeList}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) {
+			total, _, err := sumList(args[0].([]interface{}))
+			return total, err
+		}})
+	r.MustRegister(Function{Name: "avg", Params: []Type{TypeList}, Returns: TypeNumber,
+		Call: func(args []interface{}) (interface{}, error) {
+			total, count, err := sumList(args[0].([]interface{}))
+			if err != nil || count == 0 {
+				return nil, err
+			}
+			return total / float64(count), nil
+		}})
+	r.MustRegister(Function{Name: "first", Params: []Type{TypeList}, Returns: TypeAny,
+		Call: func(args []interface{}) (interface{}, error) {
+			if list := args[0].([]interface{}); len(list) > 0 {
+				return list[0], nil
+			}
+			return nil, nil
+		}})
+	r.MustRegister(Function{Name: "last", Params: []Type{TypeList}, Returns: TypeAny,
+		Call: func(args []interface{}) (interface{}, error) {
+			if list := args[0].([]interface{}); len(list) > 0 {
+				return list[len(list)-1], nil
+			}
+			return nil, nil
+		}})
+
+	// Time functions
+	registerTimeFunctions(r)
+
+	return r
+}
+
+// substr returns up to length characters of s starting at start. A start or
+// length beyond the end of s is clamped to it.
+func substr(args []interface{}) (interface{}, error) {
+	runes := []rune(args[0].(string))
+	start, err := characterCount("start", args[1].(float64), len(runes))
+	if err != nil {
+		return nil, err
+	}
+	length, err := characterCount("length", args[2].(float64), len(runes)-start)
+	if err != nil {
+		return nil, err
+	}
+	return string(runes[start : start+length]), nil
+}
+
+// characterCount converts a start or length of substr to a number of
+// characters, clamped to limit before converting so huge values cannot
+// overflow an int.
+func characterCount(name string, value float64, limit int) (int, error) {
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("substr %s must be a whole number, got %v", name, value)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("substr %s must not be negative, got %v", name, value)
+	}
+	if value > float64(limit) {
+		return limit, nil
+	}
+	return int(value), nil
+}
+
+// length returns the number of characters of a string, or elements of a list or object.
+func length(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		return float64(len([]rune(v))), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+
+	if list, ok := toList(args[0]); ok {
+		return float64(len(list)), nil
+	}
+	return nil, fmt.Errorf("len expects a string, list or object, got '%v' of type %T", args[0], args[0])
+}
+
+// reduceNumbers folds the numeric arguments with the given function.
+func reduceNumbers(args []interface{}, fn func(a, b float64) float64) float64 {
+	result := args[0].(float64)
+	for _, arg := range args[1:] {
+		result = fn(result, arg.(float64))
+	}
+	return result
+}
+
+// sumList adds up the numeric elements of a list, skipping missing ones.
+func sumList(list []interface{}) (total float64, count int, err error) {
+	for _, element := range list {
+		if element == nil {
+			continue
+		}
+		num, ok := toNumber(element)
+		if !ok {
+			return 0, 0, fmt.Errorf("cannot sum non-numeric value '%v' of type %T", element, element)
+		}
+		total += num
+		count++
+	}
+	return total, count, nil
+}
@@ -1,9 +1,13 @@
 package interpreter
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
 )
@@ -12,51 +16,128 @@ import (
 type Context map[string]interface{}
 
 // Interpreter evaluates the AST based on the given context data.
+// Evaluation errors are reported and treated as a non-match.
 func Interpret(node *parser.Node, context Context) bool {
-	if node == nil {
+	result, err := Evaluate(node, context)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
 		return false
 	}
+	return result
+}
+
+// Evaluate evaluates the AST based on the given context data and returns
+// any evaluation error, such as a division by zero.
+func Evaluate(node *parser.Node, context Context) (bool, error) {
+	if node == nil {
+		return false, nil
+	}
 
 	switch node.Type {
-	case "LogicalAndExpression":
+	case parser.LogicalAndExpression:
 		// AND: Both left and right must be true
-		return Interpret(node.Left, context) && Interpret(node.Right, context)
-	case "LogicalOrExpression":
+		left, err := Evaluate(node.Left, context)
+		if err != nil || !left {
+			return false, err
+		}
+		return Evaluate(node.Right, context)
+	case parser.LogicalOrExpression:
 		// OR: Either left or right must be true
-		return Interpret(node.Left, context) || Interpret(node.Right, context)
-	case "BinaryExpression":
+		left, err := Evaluate(node.Left, context)
+		if err != nil || left {
+			return left, err
+		}
+		return Evaluate(node.Right, context)
+	case parser.UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			// Arithmetic sign used as a condition: true if it yields a value
+			value, err := evaluateExpression(node, context)
+			return value != nil, err
+		}
+
+		// NOT / !: Negate the operand
+		result, err := Evaluate(node.Left, context)
+		if err != nil {
+			return false, err
+		}
+		return !result, nil
+	case parser.BinaryExpression:
 		// Binary expressions: Comparison like =, >, <, etc.
 		return evaluateBinaryExpression(node, context)
-	case "Identifier":
-		// Lookup identifier value from the context
-		value := context[node.Value]
-		return value != nil
-	case "NumericLiteral":
+	case parser.Identifier, parser.MemberExpression:
+		// Lookup the attribute value from the context: boolean attributes are
+		// used as is, any other attribute is true if present
+		value, err := evaluateExpression(node, context)
+		if result, ok := value.(bool); ok {
+			return result, err
+		}
+		return value != nil, err
+	case parser.BooleanLiteral:
+		// true / false
+		return node.Value == "true", nil
+	case parser.NullLiteral:
+		// null is never true
+		return false, nil
+	case parser.CallExpression:
+		// Function calls: use a boolean result as is, otherwise test for a value
+		value, err := evaluateExpression(node, context)
+		if result, ok := value.(bool); ok {
+			return result, err
+		}
+		return value != nil, err
+	case parser.NumericLiteral:
 		// Numeric literals will just return their value
-		return context[node.Value] != nil
-	case "StringLiteral":
+		return context[node.Value] != nil, nil
+	case parser.StringLiteral:
 		// String literals will be evaluated as strings
-		return context[node.Value] != nil
+		return context[node.Value] != nil, nil
 	default:
 		fmt.Printf("Unknown node type: %s\n", node.Type)
 	}
 
-	return false
+	return false, nil
 }
 
 // Helper function to evaluate binary expressions like =, >, <, <=, >= etc.
-func evaluateBinaryExpression(node *parser.Node, context Context) bool {
+func evaluateBinaryExpression(node *parser.Node, context Context) (bool, error) {
+	if node.Value == "IN" || node.Value == "NOT IN" {
+		return evaluateMembershipExpression(node, context)
+	}
+
 	var leftValue, rightValue interface{}
+	var err error
 	if node.Left != nil {
-		leftValue = evaluateExpression(node.Left, context)
+		leftValue, err = evaluateExpression(node.Left, context)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	if node.Right != nil {
-		rightValue = evaluateExpression(node.Right, context)
+		rightValue, err = evaluateExpression(node.Right, context)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if node.Left.Type == parser.NullLiteral || node.Right.Type == parser.NullLiteral {
+		// Null checks: a missing attribute or JSON null equals null
+		switch node.Value {
+		case "=":
+			return leftValue == nil && rightValue == nil, nil
+		case "!=", "<>":
+			return leftValue != nil || rightValue != nil, nil
+		}
+		return false, nil
 	}
 
 	if leftValue == nil || rightValue == nil {
-		return false
+		return false, nil
+	}
+
+	if isTemporal(leftValue) || isTemporal(rightValue) {
+		// Timestamps and durations, possibly against RFC 3339 / duration strings
+		return evaluateTemporalComparison(node.Value, leftValue, rightValue), nil
 	}
 
 	switch node.Value {
@@ -65,62 +146,416 @@ func evaluateBinaryExpression(node *parser.Node, context Context) bool {
 		equal, err := compareWithSameType(leftValue, rightValue)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
-			return false
+			return false, nil
 		}
-		return equal
+		return equal, nil
 	case ">":
 		// Handle greater than comparison
 		leftNum, leftOk := toNumber(leftValue)
 		rightNum, rightOk := toNumber(rightValue)
-		return leftOk && rightOk && leftNum > rightNum
+		return leftOk && rightOk && leftNum > rightNum, nil
 	case "<":
 		// Handle less than comparison
 		leftNum, leftOk := toNumber(leftValue)
 		rightNum, rightOk := toNumber(rightValue)
-		return leftOk && rightOk && leftNum < rightNum
+		return leftOk && rightOk && leftNum < rightNum, nil
 	case ">=":
 		// Handle greater than or equal to comparison
 		leftNum, leftOk := toNumber(leftValue)
 		rightNum, rightOk := toNumber(rightValue)
-		return leftOk && rightOk && leftNum >= rightNum
+		return leftOk && rightOk && leftNum >= rightNum, nil
 	case "<=":
 		// Handle less than or equal to comparison
 		leftNum, leftOk := toNumber(leftValue)
 		rightNum, rightOk := toNumber(rightValue)
-		return leftOk && rightOk && leftNum <= rightNum
-	case "!=":
-		// Handle not equal to comparison
-		return leftValue != rightValue
+		return leftOk && rightOk && leftNum <= rightNum, nil
+	case "!=", "<>":
+		// Handle not equal to comparison with the same type coercion as equality
+		equal, err := compareWithSameType(leftValue, rightValue)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return false, nil
+		}
+		return !equal, nil
+	case "CONTAINS", "STARTS_WITH", "ENDS_WITH", "LIKE", "MATCHES":
+		// Handle string matching
+		return evaluateStringExpression(node, leftValue, rightValue)
 	default:
 		fmt.Printf("Unknown operator: %s\n", node.Value)
-		return false
+		return false, nil
 	}
 }
 
 // Helper function to evaluate expressions and return their values
-func evaluateExpression(node *parser.Node, context Context) interface{} {
+func evaluateExpression(node *parser.Node, context Context) (interface{}, error) {
 	switch node.Type {
-	case "Identifier":
+	case parser.Identifier:
 		// Get the value of an identifier from the context
-		return context[node.Value]
-	case "NumericLiteral":
-		// Convert string numeric literals to a number
-		num, err := strconv.Atoi(node.Value)
-		if err != nil {
-			fmt.Printf("Error converting NumericLiteral '%s' to int: %v\n", node.Value, err)
-			return nil
+		return context[node.Value], nil
+	case parser.NumericLiteral:
+		// Convert string numeric literals to float64, the type JSON numbers decode to
+		num, ok := node.LiteralValue()
+		if !ok {
+			fmt.Printf("Error converting NumericLiteral '%s' to float64\n", node.Value)
+			return nil, nil
 		}
-		return num
-	case "StringLiteral":
+		return num, nil
+	case parser.StringLiteral:
 		// Strip quotes from string literals
-		return strings.Trim(node.Value, "'")
+		str, _ := node.LiteralValue()
+		return str, nil
+	case parser.BooleanLiteral, parser.NullLiteral:
+		// true, false and null (nil)
+		value, _ := node.LiteralValue()
+		return value, nil
+	case parser.DurationLiteral:
+		// Convert duration literals such as 30d to time.Duration
+		duration, ok := node.LiteralValue()
+		if !ok {
+			return nil, fmt.Errorf("invalid duration literal: '%s'", node.Value)
+		}
+		return duration, nil
+	case parser.ListLiteral:
+		// Evaluate every element of the list
+		list := make([]interface{}, 0, len(node.Elements))
+		for _, element := range node.Elements {
+			value, err := evaluateExpression(element, context)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		// Arithmetic: +, -, *, /, %
+		return evaluateArithmeticExpression(node, context)
+	case parser.UnaryExpression:
+		// Arithmetic sign: -x, +x
+		return evaluateSignExpression(node, context)
+	case parser.MemberExpression:
+		// Nested attribute path: user.address.country, orders[0].total
+		return evaluateMemberExpression(node, context)
+	case parser.CallExpression:
+		// Function call from the registry: lower(name), len(tags)
+		return evaluateCallExpression(node, context)
+	}
+
+	return nil, nil
+}
+
+// Helper function to evaluate arithmetic expressions. Missing operands yield a
+// missing result, while non-numeric operands and division by zero are errors.
+func evaluateArithmeticExpression(node *parser.Node, context Context) (interface{}, error) {
+	leftValue, err := evaluateExpression(node.Left, context)
+	if err != nil {
+		return nil, err
+	}
+	rightValue, err := evaluateExpression(node.Right, context)
+	if err != nil {
+		return nil, err
+	}
+
+	if leftValue == nil || rightValue == nil {
+		return nil, nil
+	}
+
+	if isTemporal(leftValue) || isTemporal(rightValue) {
+		return evaluateTemporalArithmetic(node.Value, leftValue, rightValue)
+	}
+
+	leftNum, ok := toNumber(leftValue)
+	if !ok {
+		return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", node.Value, leftValue, leftValue)
+	}
+	rightNum, ok := toNumber(rightValue)
+	if !ok {
+		return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", node.Value, rightValue, rightValue)
+	}
+
+	switch node.Value {
+	case "+":
+		return leftNum + rightNum, nil
+	case "-":
+		return leftNum - rightNum, nil
+	case "*":
+		return leftNum * rightNum, nil
+	case "/":
+		if rightNum == 0 {
+			return nil, fmt.Errorf("division by zero: %v / %v", leftValue, rightValue)
+		}
+		return leftNum / rightNum, nil
+	case "%":
+		if rightNum == 0 {
+			return nil, fmt.Errorf("modulo by zero: %v %% %v", leftValue, rightValue)
+		}
+		return math.Mod(leftNum, rightNum), nil
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator: %s", node.Value)
+	}
+}
+
+// Helper function to evaluate IN / NOT IN. A missing left value or list never
+// matches, for either operator, just like the other comparisons.
+func evaluateMembershipExpression(node *parser.Node, context Context) (bool, error) {
+	leftValue, err := evaluateExpression(node.Left, context)
+	if err != nil {
+		return false, err
+	}
+	if leftValue == nil {
+		// A missing value is only in a list literal holding null
+		return node.Value == "IN" && holdsNull(node.Right), nil
+	}
+
+	found, present, err := containsValue(node.Right, leftValue, context)
+	if err != nil || !present {
+		return false, err
+	}
+
+	if node.Value == "NOT IN" {
+		return !found, nil
+	}
+	return found, nil
+}
+
+// Helper function to test whether a list literal has null among its elements
+func holdsNull(listNode *parser.Node) bool {
+	return listNode != nil && listNode.Type == parser.ListLiteral && listNode.Set != nil && listNode.Set.Contains(nil)
+}
+
+// Helper function to test list membership. List literals use the hash set
+// built by the parser and only evaluate their non-constant elements, while
+// lists coming from the context are scanned. present is false if the list
+// itself is missing from the context.
+func containsValue(listNode *parser.Node, value interface{}, context Context) (found, present bool, err error) {
+	var candidates []interface{}
+
+	if listNode.Type == parser.ListLiteral && listNode.Set != nil {
+		key := normalizeNumber(value)
+		if i, ok := key.(int); ok {
+			key = float64(i)
+		}
+		if listNode.Set.Contains(key) {
+			return true, true, nil
+		}
+
+		for _, element := range listNode.Set.Dynamic {
+			candidate, err := evaluateExpression(element, context)
+			if err != nil {
+				return false, false, err
+			}
+			candidates = append(candidates, candidate)
+		}
+	} else {
+		listValue, err := evaluateExpression(listNode, context)
+		if err != nil || listValue == nil {
+			return false, false, err
+		}
+
+		var ok bool
+		candidates, ok = toList(listValue)
+		if !ok {
+			return false, false, fmt.Errorf("right side of IN must be a list, got '%v' of type %T", listValue, listValue)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate == nil {
+			continue
+		}
+		if equal, err := compareWithSameType(value, candidate); err == nil && equal {
+			return true, true, nil
+		}
+	}
+
+	return false, true, nil
+}
+
+// Helper function to evaluate string matching operators. CONTAINS also tests
+// list membership when the left side is a list. Non-string values never match.
+func evaluateStringExpression(node *parser.Node, leftValue, rightValue interface{}) (bool, error) {
+	if node.Value == "CONTAINS" {
+		if list, ok := toList(leftValue); ok {
+			for _, element := range list {
+				if equal, err := compareWithSameType(element, rightValue); err == nil && equal {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+
+	left, ok := leftValue.(string)
+	if !ok {
+		return false, nil
+	}
+
+	switch node.Value {
+	case "LIKE", "MATCHES":
+		if node.Pattern == nil {
+			return false, fmt.Errorf("%s pattern %s was not compiled", node.Value, node.Right.Value)
+		}
+		return node.Pattern.MatchString(left), nil
+	}
+
+	right, ok := rightValue.(string)
+	if !ok {
+		return false, nil
+	}
+
+	switch node.Value {
+	case "CONTAINS":
+		return strings.Contains(left, right), nil
+	case "STARTS_WITH":
+		return strings.HasPrefix(left, right), nil
+	case "ENDS_WITH":
+		return strings.HasSuffix(left, right), nil
+	}
+
+	return false, fmt.Errorf("unknown string operator: %s", node.Value)
+}
+
+// Helper function to convert a JSON array or any Go slice to []interface{}
+func toList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+	return list, true
+}
+
+// Helper function to evaluate a leading '+' or '-' applied to a numeric operand
+func evaluateSignExpression(node *parser.Node, context Context) (interface{}, error) {
+	value, err := evaluateExpression(node.Left, context)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	if duration, ok := value.(time.Duration); ok {
+		if node.Value == "-" {
+			return -duration, nil
+		}
+		return duration, nil
+	}
+
+	num, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", node.Value, value, value)
 	}
 
-	return nil
+	switch node.Value {
+	case "-":
+		return -num, nil
+	case "+":
+		return num, nil
+	default:
+		return nil, fmt.Errorf("unknown unary operator in expression: %s", node.Value)
+	}
+}
+
+// Helper function to resolve one step of an attribute path. Missing keys,
+// out of range indexes and access into values that are not maps or lists
+// resolve to nil, exactly like a missing top-level attribute, so the
+// surrounding comparison is simply false. An index of the wrong type (a
+// fractional number into a list, a number into a map) is an error.
+func evaluateMemberExpression(node *parser.Node, context Context) (interface{}, error) {
+	object, err := evaluateExpression(node.Left, context)
+	if err != nil || object == nil {
+		return nil, err
+	}
+
+	var key interface{}
+	if node.Value == "." {
+		key = node.Right.Value
+	} else {
+		key, err = evaluateExpression(node.Right, context)
+		if err != nil || key == nil {
+			return nil, err
+		}
+	}
+
+	return lookupMember(object, key)
+}
+
+// Helper function to look up a key in a map or an index in a list, covering
+// the shapes produced by encoding/json as well as hand-built Go values
+func lookupMember(object, key interface{}) (interface{}, error) {
+	switch o := object.(type) {
+	case map[string]interface{}:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index object with '%v' of type %T, expected a string key", key, key)
+		}
+		return o[name], nil
+	case Context:
+		return lookupMember(map[string]interface{}(o), key)
+	case []interface{}:
+		index, err := toIndex(key)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(o) {
+			return nil, nil
+		}
+		return o[index], nil
+	}
+
+	value := reflect.ValueOf(object)
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, nil
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index object with '%v' of type %T, expected a string key", key, key)
+		}
+		element := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		if !element.IsValid() {
+			return nil, nil
+		}
+		return element.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		index, err := toIndex(key)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= value.Len() {
+			return nil, nil
+		}
+		return value.Index(index).Interface(), nil
+	}
+
+	return nil, nil
+}
+
+// Helper function to convert an index value to a list position
+func toIndex(key interface{}) (int, error) {
+	switch k := normalizeNumber(key).(type) {
+	case int:
+		return k, nil
+	case float64:
+		if k != math.Trunc(k) {
+			return 0, fmt.Errorf("cannot index list with non-integer %v", k)
+		}
+		return int(k), nil
+	}
+
+	return 0, fmt.Errorf("cannot index list with '%v' of type %T, expected an integer", key, key)
 }
 
 // Helper function to compare two values by first trying to make them the same type
 func compareWithSameType(leftValue, rightValue interface{}) (bool, error) {
+	leftValue, rightValue = normalizeNumber(leftValue), normalizeNumber(rightValue)
+
 	switch left := leftValue.(type) {
 	case int:
 		switch right := rightValue.(type) {
@@ -148,6 +583,12 @@ func compareWithSameType(leftValue, rightValue interface{}) (bool, error) {
 			return false, fmt.Errorf("cannot convert right value '%v' of type %T to string", rightValue, rightValue)
 		}
 		return left == right, nil
+	case bool:
+		right, ok := rightValue.(bool)
+		if !ok {
+			return false, fmt.Errorf("cannot convert right value '%v' of type %T to bool", rightValue, rightValue)
+		}
+		return left == right, nil
 	default:
 		return false, fmt.Errorf("unsupported types for comparison: %T and %T", leftValue, rightValue)
 	}
@@ -155,7 +596,7 @@ func compareWithSameType(leftValue, rightValue interface{}) (bool, error) {
 
 // Convert a value to a number if possible (handles both int and float64)
 func toNumber(value interface{}) (float64, bool) {
-	switch v := value.(type) {
+	switch v := normalizeNumber(value).(type) {
 	case int:
 		return float64(v), true // Convert int to float64 for uniform comparison
 	case float64:
@@ -167,3 +608,36 @@ func toNumber(value interface{}) (float64, bool) {
 
 	return 0, false
 }
+
+// Convert Go numeric types other than int and float64 (e.g. int64 or float32
+// from callers building contexts by hand) to float64, leaving other values as is
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if num, err := v.Float64(); err == nil {
+			return num
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,222 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+var (
+	clockMu sync.RWMutex
+	clock   = time.Now
+)
+
+// SetClock replaces the clock used by now(), so tests can evaluate time
+// based rules deterministically. It returns a function restoring the
+// previous clock.
+func SetClock(now func() time.Time) (restore func()) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+
+	previous := clock
+	clock = now
+	return func() {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		clock = previous
+	}
+}
+
+// Now returns the current time according to the configured clock.
+func Now() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock()
+}
+
+// timeLayouts are the formats accepted for timestamps in rules and context
+// data: RFC 3339 (with optional fractional seconds) and plain dates in UTC.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02"}
+
+// Convert a value to a timestamp if possible (handles time.Time and RFC 3339 strings)
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Convert a value to a duration if possible (handles time.Duration and strings like "72h" or "30d")
+func toDuration(value interface{}) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		if d, err := parser.ParseDuration(v); err == nil {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+// Helper function to check whether a value is a timestamp or a duration
+func isTemporal(value interface{}) bool {
+	switch value.(type) {
+	case time.Time, time.Duration:
+		return true
+	}
+	return false
+}
+
+// Helper function to compare timestamps or durations. The other side may be
+// a string in RFC 3339 or duration syntax; anything else never matches.
+func evaluateTemporalComparison(operator string, leftValue, rightValue interface{}) bool {
+	var cmp int
+
+	if isTime(leftValue) || isTime(rightValue) {
+		left, leftOk := toTime(leftValue)
+		right, rightOk := toTime(rightValue)
+		if !leftOk || !rightOk {
+			return false
+		}
+		cmp = left.Compare(right)
+	} else {
+		left, leftOk := toDuration(leftValue)
+		right, rightOk := toDuration(rightValue)
+		if !leftOk || !rightOk {
+			return false
+		}
+		switch {
+		case left < right:
+			cmp = -1
+		case left > right:
+			cmp = 1
+		}
+	}
+
+	switch operator {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// Helper function to check whether a value is a timestamp
+func isTime(value interface{}) bool {
+	_, ok := value.(time.Time)
+	return ok
+}
+
+// Helper function to evaluate arithmetic on timestamps and durations:
+// time - time = duration, time +/- duration = time, duration +/- duration =
+// duration, and durations may be scaled by (or divided by) a number.
+func evaluateTemporalArithmetic(operator string, leftValue, rightValue interface{}) (interface{}, error) {
+	if left, ok := leftValue.(time.Time); ok {
+		if right, ok := toDuration(rightValue); ok {
+			switch operator {
+			case "+":
+				return left.Add(right), nil
+			case "-":
+				return left.Add(-right), nil
+			}
+		} else if right, ok := toTime(rightValue); ok && operator == "-" {
+			return left.Sub(right), nil
+		}
+	} else if right, ok := rightValue.(time.Time); ok {
+		if left, ok := toDuration(leftValue); ok && operator == "+" {
+			return right.Add(left), nil
+		} else if left, ok := toTime(leftValue); ok && operator == "-" {
+			return left.Sub(right), nil
+		}
+	} else if left, ok := leftValue.(time.Duration); ok {
+		if num, ok := toNumber(rightValue); ok {
+			switch operator {
+			case "*":
+				return time.Duration(float64(left) * num), nil
+			case "/":
+				if num == 0 {
+					return nil, fmt.Errorf("division by zero: %v / %v", leftValue, rightValue)
+				}
+				return time.Duration(float64(left) / num), nil
+			}
+		} else if right, ok := toTime(rightValue); ok && operator == "+" {
+			return right.Add(left), nil
+		} else if right, ok := toDuration(rightValue); ok {
+			switch operator {
+			case "+":
+				return left + right, nil
+			case "-":
+				return left - right, nil
+			case "/":
+				if right == 0 {
+					return nil, fmt.Errorf("division by zero: %v / %v", leftValue, rightValue)
+				}
+				return float64(left) / float64(right), nil
+			}
+		}
+	} else if right, ok := rightValue.(time.Duration); ok {
+		if num, ok := toNumber(leftValue); ok && operator == "*" {
+			return time.Duration(num * float64(right)), nil
+		} else if left, ok := toDuration(leftValue); ok {
+			switch operator {
+			case "+":
+				return left + right, nil
+			case "-":
+				return left - right, nil
+			}
+		} else if left, ok := toTime(leftValue); ok {
+			switch operator {
+			case "+":
+				return left.Add(right), nil
+			case "-":
+				return left.Add(-right), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("cannot apply '%s' to '%v' (%T) and '%v' (%T)", operator, leftValue, leftValue, rightValue, rightValue)
+}
+
+// registerTimeFunctions adds the date and time functions to the registry.
+func registerTimeFunctions(r *FunctionRegistry) {
+	r.MustRegister(Function{Name: "now", Params: []Type{}, Returns: TypeTime,
+		Call: func(args []interface{}) (interface{}, error) { return Now(), nil }})
+	r.MustRegister(Function{Name: "date", Params: []Type{TypeAny}, Returns: TypeTime,
+		Call: func(args []interface{}) (interface{}, error) {
+			t, ok := toTime(args[0])
+			if !ok {
+				return nil, fmt.Errorf("date expects an RFC 3339 timestamp or YYYY-MM-DD date, got '%v'", args[0])
+			}
+			return t, nil
+		}})
+	r.MustRegister(Function{Name: "duration", Params: []Type{TypeAny}, Returns: TypeDuration,
+		Call: func(args []interface{}) (interface{}, error) {
+			d, ok := toDuration(args[0])
+			if !ok {
+				return nil, fmt.Errorf("duration expects a duration such as '30d' or '1h30m', got '%v'", args[0])
+			}
+			return d, nil
+		}})
+}
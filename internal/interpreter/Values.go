@@ -0,0 +1,70 @@
+package interpreter
+
+import "github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+
+// The value semantics of the interpreter, exported so that other evaluators
+// of rules, such as the bytecode VM in internal/vm, agree with it.
+
+// ToNumber converts an int, a float, any other Go number or a numeric string
+// to a float64.
+func ToNumber(value interface{}) (float64, bool) {
+	return toNumber(value)
+}
+
+// NormalizeNumber converts Go numbers other than int and float64 to float64,
+// leaving other values as is.
+func NormalizeNumber(value interface{}) interface{} {
+	return normalizeNumber(value)
+}
+
+// Equal compares two values the way = does, converting them to the same type
+// first. An error means the values cannot be compared, which = treats as not
+// equal.
+func Equal(leftValue, rightValue interface{}) (bool, error) {
+	return compareWithSameType(leftValue, rightValue)
+}
+
+// IsTemporal reports whether a value is a timestamp or a duration.
+func IsTemporal(value interface{}) bool {
+	return isTemporal(value)
+}
+
+// CompareTemporal compares two values with a comparison operator when one of
+// them is a timestamp or a duration.
+func CompareTemporal(operator string, leftValue, rightValue interface{}) bool {
+	return evaluateTemporalComparison(operator, leftValue, rightValue)
+}
+
+// TemporalArithmetic applies an arithmetic operator when one of the values is
+// a timestamp or a duration.
+func TemporalArithmetic(operator string, leftValue, rightValue interface{}) (interface{}, error) {
+	return evaluateTemporalArithmetic(operator, leftValue, rightValue)
+}
+
+// LookupMember resolves one step of an attribute path: a key of a map or an
+// index of a list.
+func LookupMember(object, key interface{}) (interface{}, error) {
+	return lookupMember(object, key)
+}
+
+// ToList converts a JSON array or any Go slice to []interface{}.
+func ToList(value interface{}) ([]interface{}, bool) {
+	return toList(value)
+}
+
+// MatchString evaluates a CONTAINS, STARTS_WITH, ENDS_WITH, LIKE or MATCHES
+// node on the values of its two sides.
+func MatchString(node *parser.Node, leftValue, rightValue interface{}) (bool, error) {
+	return evaluateStringExpression(node, leftValue, rightValue)
+}
+
+// CheckArity verifies the number of arguments passed to the function.
+func (fn *Function) CheckArity(count int) error {
+	return fn.checkArity(count)
+}
+
+// ConvertArgument converts the i-th argument of a call to the declared
+// parameter type.
+func (fn *Function) ConvertArgument(i int, value interface{}) (interface{}, error) {
+	return convertArgument(value, fn.paramType(i))
+}
@@ -0,0 +1,160 @@
+package optimizer
+
+import (
+	"sort"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// operatorCosts is the cost of the comparisons that do more than compare two
+// values, on top of evaluating their operands.
+var operatorCosts = map[string]int{
+	"IN":          1,
+	"NOT IN":      1,
+	"CONTAINS":    3,
+	"STARTS_WITH": 3,
+	"ENDS_WITH":   3,
+	"LIKE":        8,
+	"MATCHES":     8,
+}
+
+// callCost is the cost of calling a function, on top of its arguments.
+const callCost = 10
+
+// cost estimates how expensive a node is to evaluate.
+func cost(node *parser.Node) int {
+	if node == nil {
+		return 0
+	}
+
+	switch node.Type {
+	case parser.Identifier:
+		return 1
+	case parser.MemberExpression:
+		if node.Value == "." {
+			return 1 + cost(node.Left)
+		}
+		return 1 + cost(node.Left) + cost(node.Right)
+	case parser.BinaryExpression:
+		return 1 + operatorCosts[node.Value] + cost(node.Left) + cost(node.Right)
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		return 2 + cost(node.Left) + cost(node.Right)
+	case parser.UnaryExpression:
+		return 1 + cost(node.Left)
+	case parser.LogicalAndExpression, parser.LogicalOrExpression:
+		return cost(node.Left) + cost(node.Right)
+	case parser.CallExpression:
+		total := callCost
+		for _, argument := range node.Arguments {
+			total += cost(argument)
+		}
+		return total
+	case parser.ListLiteral:
+		// Constant elements are looked up in the list's hash set
+		total := 0
+		for _, element := range node.Elements {
+			if !isConstant(element) {
+				total += cost(element)
+			}
+		}
+		return total
+	}
+	return 0
+}
+
+// canFail reports whether evaluating a condition may return an error. Only
+// comparisons of attributes and literals are known not to.
+func canFail(node *parser.Node) bool {
+	switch node.Type {
+	case parser.Identifier, parser.BooleanLiteral, parser.NullLiteral, parser.NumericLiteral, parser.StringLiteral:
+		return false
+	case parser.UnaryExpression:
+		return !isNot(node) || canFail(node.Left)
+	case parser.LogicalAndExpression, parser.LogicalOrExpression:
+		return canFail(node.Left) || canFail(node.Right)
+	case parser.BinaryExpression:
+		if !isScalar(node.Left) {
+			return true
+		}
+		switch node.Value {
+		case "IN", "NOT IN":
+			if node.Right.Type != parser.ListLiteral {
+				return true
+			}
+			for _, element := range node.Right.Elements {
+				if !isScalar(element) {
+					return true
+				}
+			}
+			return false
+		case "LIKE", "MATCHES":
+			return node.Pattern == nil
+		}
+		return !isScalar(node.Right)
+	}
+	return true
+}
+
+// isScalar reports whether a node is an attribute name or a literal.
+func isScalar(node *parser.Node) bool {
+	if node.Type == parser.Identifier {
+		return true
+	}
+	_, ok := node.LiteralValue()
+	return ok
+}
+
+// reorder sorts the operands of a chain cheapest first. Only operands that
+// cannot fail are moved, and only ahead of operands that can: moving one
+// behind them could evaluate a failing operand the chain used to skip.
+func (o *optimizer) reorder(operands []*parser.Node) []*parser.Node {
+	var pending, reordered []*parser.Node
+	flush := func(before int, limit int) {
+		// Emit the pending operands that started before the failing one
+		// or are cheaper than it, cheapest first
+		var emit, keep []*parser.Node
+		for _, operand := range pending {
+			if position(operands, operand) < before || cost(operand) < limit {
+				emit = append(emit, operand)
+			} else {
+				keep = append(keep, operand)
+			}
+		}
+		sort.SliceStable(emit, func(i, j int) bool {
+			return cost(emit[i]) < cost(emit[j])
+		})
+		reordered = append(reordered, emit...)
+		pending = keep
+	}
+
+	for _, operand := range operands {
+		if !canFail(operand) {
+			pending = append(pending, operand)
+		}
+	}
+	for i, operand := range operands {
+		if canFail(operand) {
+			flush(i, cost(operand))
+			reordered = append(reordered, operand)
+		}
+	}
+	flush(len(operands), 0)
+
+	for i := range operands {
+		if reordered[i] != operands[i] {
+			o.record(Reordering)
+			break
+		}
+	}
+	return reordered
+}
+
+// position returns the index of node itself in nodes.
+func position(nodes []*parser.Node, node *parser.Node) int {
+	for i, candidate := range nodes {
+		if candidate == node {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,463 @@
+// Package optimizer rewrites rule ASTs into equivalent ones that are cheaper
+// to evaluate. An optimized rule gives the same result as the original for
+// every context the original evaluates without an error, and never fails
+// where the original succeeds; it may succeed where the original fails, for
+// example when a division by zero is short-circuited away.
+package optimizer
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Names of the rewrites reported in Result.Rewrites.
+const (
+	// ConstantFolding evaluates arithmetic and comparisons of literals and
+	// drops true from ANDs and false from ORs
+	ConstantFolding = "constant_folding"
+	// DoubleNegation turns NOT NOT x into x
+	DoubleNegation = "double_negation"
+	// Idempotence keeps one copy of a repeated condition: x AND x is x
+	Idempotence = "idempotence"
+	// Complement folds x AND NOT x to false and x OR NOT x to true
+	Complement = "complement"
+	// Absorption drops conditions implied by others: x OR (x AND y) is x
+	Absorption = "absorption"
+	// RangeMerging keeps the tightest bound of an AND, or the loosest of an
+	// OR, on one attribute: age > 18 AND age > 21 is age > 21
+	RangeMerging = "range_merging"
+	// Reordering moves cheap conditions, which cannot fail, ahead of
+	// expensive ones so they short-circuit first
+	Reordering = "reordering"
+)
+
+// Result is the outcome of optimizing a rule.
+type Result struct {
+	// Before is the AST given to Optimize, which is left unchanged
+	Before *parser.Node
+	// After is the optimized AST
+	After *parser.Node
+	// Rewrites lists the rewrites applied, in the order they first applied
+	Rewrites []string
+}
+
+// Changed reports whether any rewrite applied.
+func (r *Result) Changed() bool {
+	return len(r.Rewrites) > 0
+}
+
+// Optimize returns an optimized copy of the rule's AST.
+func Optimize(node *parser.Node) *Result {
+	o := &optimizer{}
+	return &Result{Before: node, After: o.condition(node), Rewrites: o.rewrites}
+}
+
+// optimizer records the rewrites applied while optimizing one rule.
+type optimizer struct {
+	rewrites []string
+}
+
+// record notes that a rewrite applied.
+func (o *optimizer) record(rewrite string) {
+	for _, applied := range o.rewrites {
+		if applied == rewrite {
+			return
+		}
+	}
+	o.rewrites = append(o.rewrites, rewrite)
+}
+
+// condition optimizes a node evaluated as a condition, where it yields true
+// or false.
+func (o *optimizer) condition(node *parser.Node) *parser.Node {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Type {
+	case parser.LogicalAndExpression, parser.LogicalOrExpression:
+		return o.chain(node)
+	case parser.UnaryExpression:
+		if isNot(node) {
+			return o.not(node)
+		}
+	case parser.BinaryExpression:
+		return o.comparison(node)
+	case parser.NullLiteral:
+		// null is never true
+		o.record(ConstantFolding)
+		return boolean(false)
+	}
+
+	// Anything else is only optimized inside: folding -5 or 2 + 3 into a
+	// literal would turn it into a lookup of the attribute "-5" or "5"
+	return o.children(node)
+}
+
+// value optimizes a node evaluated for its value, folding arithmetic on
+// numeric literals.
+func (o *optimizer) value(node *parser.Node) *parser.Node {
+	if node == nil {
+		return nil
+	}
+
+	copied := o.children(node)
+	switch node.Type {
+	case parser.ListLiteral:
+		// Rebuild the list so its hash set holds the folded elements
+		list := parser.NewListLiteral(o.values(node.Elements))
+		list.Start, list.End = node.Start, node.End
+		return list
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		if folded, ok := foldArithmetic(copied); ok {
+			o.record(ConstantFolding)
+			return folded
+		}
+	case parser.UnaryExpression:
+		if folded, ok := foldSign(copied); ok {
+			o.record(ConstantFolding)
+			return folded
+		}
+	}
+	return copied
+}
+
+// children returns a copy of a node with its operands and arguments
+// optimized for their values.
+func (o *optimizer) children(node *parser.Node) *parser.Node {
+	copied := *node
+	copied.Left = o.value(node.Left)
+	copied.Right = o.value(node.Right)
+	copied.Arguments = o.values(node.Arguments)
+	return &copied
+}
+
+// values optimizes each node of a list for its value.
+func (o *optimizer) values(nodes []*parser.Node) []*parser.Node {
+	if nodes == nil {
+		return nil
+	}
+	optimized := make([]*parser.Node, len(nodes))
+	for i, node := range nodes {
+		optimized[i] = o.value(node)
+	}
+	return optimized
+}
+
+// not optimizes a negation.
+func (o *optimizer) not(node *parser.Node) *parser.Node {
+	operand := o.condition(node.Left)
+	if operand.Type == parser.BooleanLiteral {
+		o.record(ConstantFolding)
+		return boolean(operand.Value != "true")
+	}
+	if isNot(operand) {
+		o.record(DoubleNegation)
+		return operand.Left
+	}
+
+	copied := *node
+	copied.Left = operand
+	return &copied
+}
+
+// comparison optimizes a comparison, evaluating it when both sides are
+// constant.
+func (o *optimizer) comparison(node *parser.Node) *parser.Node {
+	copied := *node
+	copied.Left = o.value(node.Left)
+	copied.Right = o.value(node.Right)
+
+	if foldableComparison(&copied) {
+		if result, err := interpreter.Evaluate(&copied, interpreter.Context{}); err == nil {
+			o.record(ConstantFolding)
+			return boolean(result)
+		}
+	}
+	return &copied
+}
+
+// chain optimizes a chain of ANDs or ORs as a whole, so that rewrites see
+// every operand of the chain at once.
+func (o *optimizer) chain(node *parser.Node) *parser.Node {
+	kind := node.Type
+	// identity, true in an AND and false in an OR, can be dropped from the
+	// chain, while its opposite decides the chain on its own
+	identity := kind == parser.LogicalAndExpression
+	dominant := !identity
+
+	var operands []*parser.Node
+	for _, operand := range operandsOf(kind, node) {
+		operands = append(operands, operandsOf(kind, o.condition(operand))...)
+	}
+
+	// Constant folding
+	kept := operands[:0]
+	for _, operand := range operands {
+		if operand.Type != parser.BooleanLiteral {
+			kept = append(kept, operand)
+			continue
+		}
+		o.record(ConstantFolding)
+		if (operand.Value == "true") == dominant {
+			return boolean(dominant)
+		}
+	}
+	operands = kept
+
+	// Idempotence
+	operands = o.dedupe(operands)
+
+	// Complement
+	for _, operand := range operands {
+		if isNot(operand) && indexOf(operands, operand.Left) >= 0 {
+			o.record(Complement)
+			return boolean(dominant)
+		}
+	}
+
+	operands = o.absorb(kind, operands)
+
+	operands, contradiction := o.mergeRanges(kind, operands)
+	if contradiction {
+		return boolean(false)
+	}
+
+	operands = o.reorder(operands)
+
+	if len(operands) == 0 {
+		return boolean(identity)
+	}
+	return join(kind, operands)
+}
+
+// dedupe keeps the first of identical operands.
+func (o *optimizer) dedupe(operands []*parser.Node) []*parser.Node {
+	var deduped []*parser.Node
+	for _, operand := range operands {
+		if indexOf(deduped, operand) >= 0 {
+			o.record(Idempotence)
+			continue
+		}
+		deduped = append(deduped, operand)
+	}
+	return deduped
+}
+
+// absorb drops the operands of a chain implied by another operand: in an OR,
+// an AND of conditions that includes every condition of another operand,
+// and the other way round in an AND.
+func (o *optimizer) absorb(kind parser.NodeKind, operands []*parser.Node) []*parser.Node {
+	inner := parser.LogicalAndExpression
+	if kind == parser.LogicalAndExpression {
+		inner = parser.LogicalOrExpression
+	}
+
+	terms := make([][]*parser.Node, len(operands))
+	for i, operand := range operands {
+		terms[i] = operandsOf(inner, operand)
+	}
+
+	dropped := make([]bool, len(operands))
+	var kept []*parser.Node
+	for i, operand := range operands {
+		for j := range operands {
+			// Of two operands with the same terms, the first is kept
+			if j != i && !dropped[j] && subset(terms[j], terms[i]) && (len(terms[j]) < len(terms[i]) || j < i) {
+				dropped[i] = true
+				break
+			}
+		}
+		if dropped[i] {
+			o.record(Absorption)
+			continue
+		}
+		kept = append(kept, operand)
+	}
+	return kept
+}
+
+// foldableComparison reports whether a comparison only involves constants
+// and can be evaluated once. Equality between literals of different types
+// is left to the interpreter, which reports the mismatch.
+func foldableComparison(node *parser.Node) bool {
+	if !isConstant(node.Left) || !isConstant(node.Right) {
+		return false
+	}
+	switch node.Value {
+	case "=", "!=", "<>":
+		if node.Left.Type == parser.NullLiteral || node.Right.Type == parser.NullLiteral {
+			return true
+		}
+		left, _ := node.Left.LiteralValue()
+		right, _ := node.Right.LiteralValue()
+		return node.Left.Type != parser.ListLiteral && node.Right.Type != parser.ListLiteral &&
+			sameType(left, right)
+	}
+	return true
+}
+
+// foldArithmetic computes arithmetic on two numeric literals. Divisions by
+// zero and results that are not finite are left to the interpreter.
+func foldArithmetic(node *parser.Node) (*parser.Node, bool) {
+	left, leftOk := number(node.Left)
+	right, rightOk := number(node.Right)
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+
+	var result float64
+	switch node.Value {
+	case "+":
+		result = left + right
+	case "-":
+		result = left - right
+	case "*":
+		result = left * right
+	case "/":
+		if right == 0 {
+			return nil, false
+		}
+		result = left / right
+	case "%":
+		if right == 0 {
+			return nil, false
+		}
+		result = math.Mod(left, right)
+	default:
+		return nil, false
+	}
+
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil, false
+	}
+	return numeric(result, node), true
+}
+
+// foldSign applies a sign to a numeric literal.
+func foldSign(node *parser.Node) (*parser.Node, bool) {
+	value, ok := number(node.Left)
+	if !ok {
+		return nil, false
+	}
+
+	switch node.Value {
+	case "-":
+		return numeric(-value, node), true
+	case "+":
+		return numeric(value, node), true
+	}
+	return nil, false
+}
+
+// isConstant reports whether a node's value does not depend on the context.
+func isConstant(node *parser.Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.Type == parser.ListLiteral {
+		for _, element := range node.Elements {
+			if !isConstant(element) {
+				return false
+			}
+		}
+		return true
+	}
+	_, ok := node.LiteralValue()
+	return ok
+}
+
+// isNot reports whether a node is a logical negation.
+func isNot(node *parser.Node) bool {
+	return node.Type == parser.UnaryExpression && (node.Value == "NOT" || node.Value == "!")
+}
+
+// number returns the value of a numeric literal.
+func number(node *parser.Node) (float64, bool) {
+	if node == nil || node.Type != parser.NumericLiteral {
+		return 0, false
+	}
+	value, ok := node.LiteralValue()
+	if !ok {
+		return 0, false
+	}
+	return value.(float64), true
+}
+
+// sameType reports whether two literal values have the same Go type.
+func sameType(a, b interface{}) bool {
+	switch a.(type) {
+	case float64:
+		_, ok := b.(float64)
+		return ok
+	case string:
+		_, ok := b.(string)
+		return ok
+	case bool:
+		_, ok := b.(bool)
+		return ok
+	}
+	return false
+}
+
+// boolean creates a boolean literal.
+func boolean(value bool) *parser.Node {
+	return &parser.Node{Type: parser.BooleanLiteral, Value: strconv.FormatBool(value)}
+}
+
+// numeric creates a numeric literal spanning the node it replaces.
+func numeric(value float64, replaced *parser.Node) *parser.Node {
+	return &parser.Node{
+		Type:  parser.NumericLiteral,
+		Value: parser.FormatNumber(value),
+		Start: replaced.Start,
+		End:   replaced.End,
+	}
+}
+
+// operandsOf returns the operands of a chain of kind, or the node itself if
+// it is not such a chain.
+func operandsOf(kind parser.NodeKind, node *parser.Node) []*parser.Node {
+	if node.Type != kind {
+		return []*parser.Node{node}
+	}
+	return append(operandsOf(kind, node.Left), operandsOf(kind, node.Right)...)
+}
+
+// join chains operands with a logical operator, left to right, the way the
+// parser groups them.
+func join(kind parser.NodeKind, operands []*parser.Node) *parser.Node {
+	operator := "OR"
+	if kind == parser.LogicalAndExpression {
+		operator = "AND"
+	}
+
+	joined := operands[0]
+	for _, operand := range operands[1:] {
+		joined = &parser.Node{Type: kind, Value: operator, Left: joined, Right: operand}
+	}
+	return joined
+}
+
+// indexOf returns the index of the first node Equal to node, or -1.
+func indexOf(nodes []*parser.Node, node *parser.Node) int {
+	for i, candidate := range nodes {
+		if parser.Equal(candidate, node) {
+			return i
+		}
+	}
+	return -1
+}
+
+// subset reports whether every node of a is Equal to a node of b.
+func subset(a, b []*parser.Node) bool {
+	for _, node := range a {
+		if indexOf(b, node) < 0 {
+			return false
+		}
+	}
+	return true
+}
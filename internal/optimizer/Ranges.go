@@ -0,0 +1,170 @@
+package optimizer
+
+import "github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+
+// flippedOperators turns a comparison around, for bounds written with the
+// number first: 18 < age is age > 18.
+var flippedOperators = map[string]string{
+	">":  "<",
+	">=": "<=",
+	"<":  ">",
+	"<=": ">=",
+}
+
+// bound is a comparison of an attribute against a number, age >= 18.
+type bound struct {
+	node      *parser.Node
+	index     int
+	attribute *parser.Node
+	// lower is true for > and >=, false for < and <=
+	lower  bool
+	strict bool
+	value  float64
+}
+
+// rangeGroup holds the bounds on one side of one attribute.
+type rangeGroup struct {
+	attribute *parser.Node
+	lower     bool
+	bounds    []bound
+}
+
+// tighter reports whether b excludes more values than other, for bounds on
+// the same side.
+func (b bound) tighter(other bound) bool {
+	if b.value == other.value {
+		return b.strict && !other.strict
+	}
+	return (b.value > other.value) == b.lower
+}
+
+// mergeRanges replaces the bounds on one side of one attribute by the
+// tightest of them in an AND, or the loosest in an OR, at the place of the
+// first of them. A value that is not a number fails every bound, so the
+// merged bound keeps the result of the original ones. contradiction is true
+// when the bounds of an AND leave no value at all.
+func (o *optimizer) mergeRanges(kind parser.NodeKind, operands []*parser.Node) (merged []*parser.Node, contradiction bool) {
+	var groups []*rangeGroup
+	for i, operand := range operands {
+		b, ok := rangeBound(operand)
+		if !ok {
+			continue
+		}
+		b.index = i
+
+		group := findGroup(groups, b)
+		if group == nil {
+			group = &rangeGroup{attribute: b.attribute, lower: b.lower}
+			groups = append(groups, group)
+		}
+		group.bounds = append(group.bounds, b)
+	}
+
+	// The chosen bound takes the place of the group's first bound
+	replaced := make(map[int]*parser.Node)
+	dropped := make(map[int]bool)
+	chosen := make([]bound, len(groups))
+	for g, group := range groups {
+		best := group.bounds[0]
+		for _, b := range group.bounds[1:] {
+			if b.tighter(best) == (kind == parser.LogicalAndExpression) && !sameBound(b, best) {
+				best = b
+			}
+			dropped[b.index] = true
+		}
+		if len(group.bounds) > 1 {
+			o.record(RangeMerging)
+		}
+		replaced[group.bounds[0].index] = best.node
+		chosen[g] = best
+	}
+
+	if kind == parser.LogicalAndExpression {
+		for _, lower := range chosen {
+			for _, upper := range chosen {
+				if lower.lower && !upper.lower && parser.Equal(lower.attribute, upper.attribute) && empty(lower, upper) {
+					o.record(RangeMerging)
+					return nil, true
+				}
+			}
+		}
+	}
+
+	for i, operand := range operands {
+		if node, ok := replaced[i]; ok {
+			merged = append(merged, node)
+		} else if !dropped[i] {
+			merged = append(merged, operand)
+		}
+	}
+	return merged, false
+}
+
+// rangeBound returns the bound a comparison puts on an attribute, if it
+// compares one against a numeric literal.
+func rangeBound(node *parser.Node) (bound, bool) {
+	if node.Type != parser.BinaryExpression {
+		return bound{}, false
+	}
+	operator := node.Value
+	if _, ok := flippedOperators[operator]; !ok {
+		return bound{}, false
+	}
+
+	attribute, literal := node.Left, node.Right
+	if !isAttribute(attribute) {
+		attribute, literal = node.Right, node.Left
+		operator = flippedOperators[operator]
+	}
+	value, ok := number(literal)
+	if !ok || !isAttribute(attribute) {
+		return bound{}, false
+	}
+
+	return bound{
+		node:      node,
+		attribute: attribute,
+		lower:     operator == ">" || operator == ">=",
+		strict:    operator == ">" || operator == "<",
+		value:     value,
+	}, true
+}
+
+// isAttribute reports whether a node reads an attribute of the context,
+// directly or through a path of names and literal indexes.
+func isAttribute(node *parser.Node) bool {
+	switch node.Type {
+	case parser.Identifier:
+		return true
+	case parser.MemberExpression:
+		if node.Value != "." && !isConstant(node.Right) {
+			return false
+		}
+		return isAttribute(node.Left)
+	}
+	return false
+}
+
+// findGroup returns the group of bounds on the same side of the same
+// attribute as b.
+func findGroup(groups []*rangeGroup, b bound) *rangeGroup {
+	for _, group := range groups {
+		if group.lower == b.lower && parser.Equal(group.attribute, b.attribute) {
+			return group
+		}
+	}
+	return nil
+}
+
+// sameBound reports whether two bounds exclude the same values.
+func sameBound(a, b bound) bool {
+	return a.value == b.value && a.strict == b.strict
+}
+
+// empty reports whether no number satisfies both a lower and an upper bound.
+func empty(lower, upper bound) bool {
+	if lower.value == upper.value {
+		return lower.strict || upper.strict
+	}
+	return lower.value > upper.value
+}
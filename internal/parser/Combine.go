@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logicalOperators maps the operators rules can be combined with to the kind
+// of node joining them.
+var logicalOperators = map[string]NodeKind{
+	"AND": LogicalAndExpression,
+	"OR":  LogicalOrExpression,
+}
+
+// Combine joins rules with a logical operator, "AND" or "OR", into a single
+// AST. Rules already joined by that operator are flattened into the result,
+// and identical conditions are kept once, in the result and in every AND or
+// OR nested in it. The rules themselves are not modified.
+func Combine(operator string, rules ...*Node) (*Node, error) {
+	kind, ok := logicalOperators[strings.ToUpper(operator)]
+	if !ok {
+		return nil, fmt.Errorf("unknown operator %q, expected AND or OR", operator)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules provided")
+	}
+
+	var operands []*Node
+	for _, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("cannot combine a missing rule")
+		}
+		operands = appendOperands(operands, kind, rule)
+	}
+	return join(kind, operands), nil
+}
+
+// MostFrequentOperator returns the logical operator, "AND" or "OR", that
+// occurs most often in the rules. Ties go to "OR".
+func MostFrequentOperator(rules ...*Node) string {
+	counts := make(map[NodeKind]int)
+	for _, rule := range rules {
+		Inspect(rule, func(node *Node) bool {
+			if node != nil {
+				counts[node.Type]++
+			}
+			return true
+		})
+	}
+
+	if counts[LogicalAndExpression] > counts[LogicalOrExpression] {
+		return "AND"
+	}
+	return "OR"
+}
+
+// Dedupe returns a copy of the AST in which every chain of ANDs or ORs keeps
+// each of its conditions once, in the order they first appear.
+func Dedupe(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	if node.Type == LogicalAndExpression || node.Type == LogicalOrExpression {
+		return join(node.Type, appendOperands(nil, node.Type, node))
+	}
+
+	copied := *node
+	copied.Left = Dedupe(node.Left)
+	copied.Right = Dedupe(node.Right)
+	copied.Arguments = dedupeList(node.Arguments)
+	return &copied
+}
+
+// appendOperands appends the operands of a chain of kind to operands, with
+// their own chains deduplicated, skipping operands already in the list.
+func appendOperands(operands []*Node, kind NodeKind, node *Node) []*Node {
+	if node.Type == kind {
+		operands = appendOperands(operands, kind, node.Left)
+		return appendOperands(operands, kind, node.Right)
+	}
+
+	node = Dedupe(node)
+	for _, operand := range operands {
+		if Equal(operand, node) {
+			return operands
+		}
+	}
+	return append(operands, node)
+}
+
+// join chains operands with a logical operator, left to right, the way the
+// parser groups them.
+func join(kind NodeKind, operands []*Node) *Node {
+	operator := "OR"
+	if kind == LogicalAndExpression {
+		operator = "AND"
+	}
+
+	joined := operands[0]
+	for _, operand := range operands[1:] {
+		joined = &Node{Type: kind, Value: operator, Left: joined, Right: operand}
+	}
+	return joined
+}
+
+// dedupeList deduplicates the chains in each node of a list.
+func dedupeList(nodes []*Node) []*Node {
+	if nodes == nil {
+		return nil
+	}
+	deduped := make([]*Node, len(nodes))
+	for i, node := range nodes {
+		deduped[i] = Dedupe(node)
+	}
+	return deduped
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostic codes reported by the tokenizer and parser.
+const (
+	CodeEmptyInput         = "empty-input"
+	CodeIllegalCharacter   = "illegal-character"
+	CodeUnterminatedString = "unterminated-string"
+	CodeUnexpectedToken    = "unexpected-token"
+	CodeUnexpectedEnd      = "unexpected-end"
+	CodeTrailingInput      = "trailing-input"
+	CodeInvalidPattern     = "invalid-pattern"
+	CodeInvalidLiteral     = "invalid-literal"
+	CodeTypeError          = "type-error"
+)
+
+// Diagnostic describes one problem in a rule string. Line and Column are
+// 1-based and point at the start of the problem; Start and End are the byte
+// span in the rule string that a UI should underline.
+type Diagnostic struct {
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Error formats the diagnostic as "line:column: message".
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// NewDiagnostic creates a diagnostic for the byte span [start, end) of
+// source, e.g. the span of an AST node that failed a later check.
+func NewDiagnostic(source string, start, end int, code, message, suggestion string) *Diagnostic {
+	line, column := position(source, start)
+	return &Diagnostic{
+		Line:       line,
+		Column:     column,
+		Start:      start,
+		End:        end,
+		Code:       code,
+		Message:    message,
+		Suggestion: suggestion,
+	}
+}
+
+// Diagnostics is every problem found in a rule string. ParseRule returns it
+// as its error so callers can report all problems at once.
+type Diagnostics []*Diagnostic
+
+// Error joins the messages of all diagnostics.
+func (d Diagnostics) Error() string {
+	messages := make([]string, len(d))
+	for i, diagnostic := range d {
+		messages[i] = diagnostic.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// suggestionFor returns a hint for common mistakes, given the offending
+// token and what the parser expected instead.
+func suggestionFor(token *Token, expected string) string {
+	if token == nil {
+		if expected == "')'" || expected == "']'" {
+			return "add a closing " + expected
+		}
+		return "complete the expression"
+	}
+
+	switch token.Value {
+	case "&":
+		return "use AND or && for a logical and"
+	case "|":
+		return "use OR or || for a logical or"
+	case "=":
+		return "use a single '=' to compare values"
+	case "and", "or", "not", "in", "like", "contains", "matches":
+		return fmt.Sprintf("keywords are uppercase: use %s", strings.ToUpper(token.Value))
+	}
+
+	switch token.Type {
+	case "UNTERMINATED_STRING":
+		return fmt.Sprintf("add a closing %c", token.Value[0])
+	case "RELATIONAL_OPERATOR", "EQUALITY_OPERATOR":
+		return "comparisons take a single operator between two values"
+	}
+
+	if expected == "')'" || expected == "']'" {
+		return "add a closing " + expected
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package parser
+
+import "strconv"
+
+// Difference is a subtree that changed between two ASTs. Path is the JSON
+// pointer of the subtree, and Before and After are its formatted rule text
+// ("" when the subtree was added or removed).
+type Difference struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Diff returns the smallest subtrees that differ between two ASTs. Nodes
+// with the same type, value and number of children are compared child by
+// child; any other change replaces the whole subtree.
+func Diff(before, after *Node) []Difference {
+	var differences []Difference
+	diff(before, after, "", &differences)
+	return differences
+}
+
+// diff appends the differences between two subtrees at path.
+func diff(before, after *Node, path string, differences *[]Difference) {
+	if Equal(before, after) {
+		return
+	}
+
+	if before == nil || after == nil || !sameNode(before, after) ||
+		(before.Left == nil) != (after.Left == nil) || (before.Right == nil) != (after.Right == nil) ||
+		len(before.Elements) != len(after.Elements) || len(before.Arguments) != len(after.Arguments) {
+		*differences = append(*differences, Difference{Path: path, Before: Format(before), After: Format(after)})
+		return
+	}
+
+	diff(before.Left, after.Left, path+"/Left", differences)
+	diff(before.Right, after.Right, path+"/Right", differences)
+	for i := range before.Elements {
+		diff(before.Elements[i], after.Elements[i], path+"/Elements/"+strconv.Itoa(i), differences)
+	}
+	for i := range before.Arguments {
+		diff(before.Arguments[i], after.Arguments[i], path+"/Arguments/"+strconv.Itoa(i), differences)
+	}
+}
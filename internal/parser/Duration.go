@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)(ms|s|m|h|d|w)`)
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// ParseDuration parses durations written as a sequence of numbers with a
+// unit, such as 30d, 12h or 1h30m. Besides the units time.ParseDuration
+// knows it accepts d (24 hours) and w (7 days).
+func ParseDuration(value string) (time.Duration, error) {
+	parts := durationPart.FindAllStringSubmatchIndex(value, -1)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid duration: '%s'", value)
+	}
+
+	var total float64
+	end := 0
+	for _, part := range parts {
+		if part[0] != end {
+			return 0, fmt.Errorf("invalid duration: '%s'", value)
+		}
+		end = part[1]
+
+		amount, err := strconv.ParseFloat(value[part[2]:part[3]], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: '%s'", value)
+		}
+		total += amount * float64(durationUnits[value[part[4]:part[5]]])
+	}
+
+	if end != len(value) {
+		return 0, fmt.Errorf("invalid duration: '%s'", value)
+	}
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("duration out of range: '%s'", value)
+	}
+
+	return time.Duration(total), nil
+}
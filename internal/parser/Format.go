@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Operator precedence levels, lowest first, mirroring the Parser's grammar.
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceNot
+	precedenceEquality
+	precedenceRelational
+	precedenceAdditive
+	precedenceMultiplicative
+	precedencePrefix
+	precedencePrimary
+)
+
+// canonicalOperators maps operator spellings to the one Format writes.
+var canonicalOperators = map[string]string{
+	"&&": "AND",
+	"||": "OR",
+	"!":  "NOT",
+	"<>": "!=",
+}
+
+// Format turns an AST back into a canonical rule string: keywords are
+// uppercase, strings single quoted (double quoted if they contain a single
+// quote), numbers in their shortest form, one space around binary operators
+// and only the parentheses the grammar requires. Parsing the result yields an
+// AST Equal to node.
+func Format(node *Node) string {
+	var builder strings.Builder
+	format(&builder, node)
+	return builder.String()
+}
+
+// format writes node to builder.
+func format(b *strings.Builder, node *Node) {
+	if node == nil {
+		return
+	}
+
+	switch node.Type {
+	case LogicalOrExpression, LogicalAndExpression, BinaryExpression, AdditiveExpression, MultiplicativeExpression:
+		level := precedence(node)
+		formatOperand(b, node.Left, level)
+		b.WriteString(" " + canonicalOperator(node.Value) + " ")
+		// Operators are left associative, so an equal precedence right operand needs parentheses
+		formatOperand(b, node.Right, level+1)
+	case UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			b.WriteString(node.Value)
+			// A sign directly before a number would be folded into the literal
+			if node.Left != nil && node.Left.Type == NumericLiteral {
+				formatOperand(b, node.Left, precedencePrimary+1)
+			} else {
+				formatOperand(b, node.Left, precedencePrefix)
+			}
+			return
+		}
+		b.WriteString(canonicalOperator(node.Value) + " ")
+		formatOperand(b, node.Left, precedenceNot)
+	case MemberExpression:
+		formatOperand(b, node.Left, precedencePrimary)
+		if node.Value == "." {
+			b.WriteString(".")
+			format(b, node.Right)
+			return
+		}
+		b.WriteString("[")
+		format(b, node.Right)
+		b.WriteString("]")
+	case CallExpression:
+		b.WriteString(node.Value + "(")
+		formatList(b, node.Arguments, precedenceOr)
+		b.WriteString(")")
+	case ListLiteral:
+		b.WriteString("[")
+		formatList(b, node.Elements, precedenceAdditive)
+		b.WriteString("]")
+	case StringLiteral:
+		value, _ := node.LiteralValue()
+		b.WriteString(quote(value.(string)))
+	case NumericLiteral:
+		if value, ok := node.LiteralValue(); ok {
+			b.WriteString(FormatNumber(value.(float64)))
+			return
+		}
+		b.WriteString(node.Value)
+	default:
+		// Identifiers, durations, booleans and null are written as is
+		b.WriteString(node.Value)
+	}
+}
+
+// FormatNumber writes a number in plain decimal notation, like 1500000 or
+// 0.0000001. Only magnitudes too large or too small to write out sensibly
+// use exponent notation.
+func FormatNumber(value float64) string {
+	if abs := math.Abs(value); abs >= 1e21 || abs != 0 && abs < 1e-15 {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatOperand writes node, wrapped in parentheses if it binds looser than
+// the minimum precedence its position requires.
+func formatOperand(b *strings.Builder, node *Node, minimum int) {
+	if node != nil && precedence(node) < minimum {
+		b.WriteString("(")
+		format(b, node)
+		b.WriteString(")")
+		return
+	}
+	format(b, node)
+}
+
+// formatList writes comma separated nodes.
+func formatList(b *strings.Builder, nodes []*Node, minimum int) {
+	for i, node := range nodes {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		formatOperand(b, node, minimum)
+	}
+}
+
+// precedence returns how tightly the node's operator binds.
+func precedence(node *Node) int {
+	switch node.Type {
+	case LogicalOrExpression:
+		return precedenceOr
+	case LogicalAndExpression:
+		return precedenceAnd
+	case UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			return precedencePrefix
+		}
+		return precedenceNot
+	case BinaryExpression:
+		switch node.Value {
+		case "=", "!=", "<>":
+			return precedenceEquality
+		}
+		return precedenceRelational
+	case AdditiveExpression:
+		return precedenceAdditive
+	case MultiplicativeExpression:
+		return precedenceMultiplicative
+	}
+	return precedencePrimary
+}
+
+// canonicalOperator returns the spelling Format uses for an operator.
+func canonicalOperator(operator string) string {
+	if canonical, ok := canonicalOperators[operator]; ok {
+		return canonical
+	}
+	return operator
+}
+
+// quote wraps a string in single quotes, or double quotes if it contains a
+// single quote. The tokenizer has no escapes, so this is always reversible
+// unless the string contains both kinds of quotes.
+func quote(value string) string {
+	if strings.Contains(value, "'") {
+		return `"` + value + `"`
+	}
+	return "'" + value + "'"
+}
+
+// Equal reports whether two ASTs have the same structure and values,
+// ignoring source spans, operator spellings (AND / &&) and the quoting or
+// spelling of literals (4.50 / 4.5).
+func Equal(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !sameNode(a, b) {
+		return false
+	}
+
+	if !Equal(a.Left, b.Left) || !Equal(a.Right, b.Right) {
+		return false
+	}
+	return equalList(a.Elements, b.Elements) && equalList(a.Arguments, b.Arguments)
+}
+
+// sameNode reports whether two nodes have the same type and value, ignoring
+// their children.
+func sameNode(a, b *Node) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	if aValue, ok := a.LiteralValue(); ok {
+		bValue, _ := b.LiteralValue()
+		return aValue == bValue
+	}
+	return canonicalOperator(a.Value) == canonicalOperator(b.Value)
+}
+
+// equalList reports whether two node lists are pairwise Equal.
+func equalList(a, b []*Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
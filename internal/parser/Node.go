@@ -0,0 +1,412 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NodeKind identifies the type of an AST node. In JSON it is written as the
+// name of the kind, e.g. "BinaryExpression".
+type NodeKind uint8
+
+const (
+	InvalidNode NodeKind = iota
+	LogicalOrExpression
+	LogicalAndExpression
+	UnaryExpression
+	BinaryExpression
+	AdditiveExpression
+	MultiplicativeExpression
+	MemberExpression
+	CallExpression
+	ListLiteral
+	Identifier
+	NumericLiteral
+	DurationLiteral
+	StringLiteral
+	BooleanLiteral
+	NullLiteral
+)
+
+var kindNames = [...]string{
+	InvalidNode:              "InvalidNode",
+	LogicalOrExpression:      "LogicalOrExpression",
+	LogicalAndExpression:     "LogicalAndExpression",
+	UnaryExpression:          "UnaryExpression",
+	BinaryExpression:         "BinaryExpression",
+	AdditiveExpression:       "AdditiveExpression",
+	MultiplicativeExpression: "MultiplicativeExpression",
+	MemberExpression:         "MemberExpression",
+	CallExpression:           "CallExpression",
+	ListLiteral:              "ListLiteral",
+	Identifier:               "Identifier",
+	NumericLiteral:           "NumericLiteral",
+	DurationLiteral:          "DurationLiteral",
+	StringLiteral:            "StringLiteral",
+	BooleanLiteral:           "BooleanLiteral",
+	NullLiteral:              "NullLiteral",
+}
+
+// ParseNodeKind returns the kind with the given name.
+func ParseNodeKind(name string) (NodeKind, bool) {
+	for kind, kindName := range kindNames {
+		if kind != int(InvalidNode) && kindName == name {
+			return NodeKind(kind), true
+		}
+	}
+	return InvalidNode, false
+}
+
+// String returns the name of the kind.
+func (k NodeKind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "NodeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// MarshalText writes the kind as its name.
+func (k NodeKind) MarshalText() ([]byte, error) {
+	if k == InvalidNode || int(k) >= len(kindNames) {
+		return nil, fmt.Errorf("cannot marshal node kind %s", k)
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText reads a kind from its name.
+func (k *NodeKind) UnmarshalText(text []byte) error {
+	kind, ok := ParseNodeKind(string(text))
+	if !ok {
+		return fmt.Errorf("unknown node type: %s", text)
+	}
+	*k = kind
+	return nil
+}
+
+// Node represents a node in the Abstract Syntax Tree.
+// Every node records the span of the rule string it was parsed from.
+// Unary nodes keep their single operand in Left, list literals keep their
+// items in Elements along with a membership Set built once at parse time,
+// calls keep their Arguments, and LIKE / MATCHES expressions carry their
+// compiled Pattern.
+type Node struct {
+	Type      NodeKind
+	Value     string
+	Left      *Node
+	Right     *Node
+	Elements  []*Node `json:",omitempty"`
+	Arguments []*Node `json:",omitempty"`
+
+	// Start and End are the byte offsets of the node in the rule string.
+	Start int
+	End   int
+
+	Set     *ValueSet      `json:"-"`
+	Pattern *regexp.Regexp `json:"-"`
+}
+
+// LiteralValue returns the Go value of a literal node: float64 for numbers,
+// the unquoted text for strings, time.Duration for durations, bool for
+// booleans and nil for null. ok is false for any other node.
+func (n *Node) LiteralValue() (value interface{}, ok bool) {
+	switch n.Type {
+	case NumericLiteral:
+		num, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return nil, false
+		}
+		return num, true
+	case StringLiteral:
+		return unquote(n.Value), true
+	case DurationLiteral:
+		duration, err := ParseDuration(n.Value)
+		if err != nil {
+			return nil, false
+		}
+		return duration, true
+	case BooleanLiteral:
+		return n.Value == "true", true
+	case NullLiteral:
+		return nil, true
+	}
+
+	return nil, false
+}
+
+// unquote strips one pair of matching single or double quotes, if present.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// Children returns the direct children of the node in source order.
+func (n *Node) Children() []*Node {
+	var children []*Node
+	if n.Left != nil {
+		children = append(children, n.Left)
+	}
+	if n.Right != nil {
+		children = append(children, n.Right)
+	}
+	children = append(children, n.Elements...)
+	return append(children, n.Arguments...)
+}
+
+// A Visitor's Visit method is called for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node *Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order.
+func Walk(v Visitor, node *Node) {
+	if node == nil {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+// inspector adapts a function to the Visitor interface.
+type inspector func(*Node) bool
+
+func (f inspector) Visit(node *Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order, calling f for each node
+// and then f(nil) after its children. The children of a node are skipped
+// when f returns false.
+func Inspect(node *Node, f func(*Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// nodeShape lists the fields a node kind uses. Operands marked as used are
+// required; fields not used must be empty.
+type nodeShape struct {
+	left      bool
+	right     bool
+	elements  bool
+	arguments bool
+	operator  bool // Value is required
+	literal   bool // Value must hold a valid literal
+}
+
+var shapes = map[NodeKind]nodeShape{
+	LogicalOrExpression:      {left: true, right: true, operator: true},
+	LogicalAndExpression:     {left: true, right: true, operator: true},
+	UnaryExpression:          {left: true, operator: true},
+	BinaryExpression:         {left: true, right: true, operator: true},
+	AdditiveExpression:       {left: true, right: true, operator: true},
+	MultiplicativeExpression: {left: true, right: true, operator: true},
+	MemberExpression:         {left: true, right: true, operator: true},
+	CallExpression:           {arguments: true, operator: true},
+	ListLiteral:              {elements: true},
+	Identifier:               {operator: true},
+	NumericLiteral:           {literal: true},
+	DurationLiteral:          {literal: true},
+	StringLiteral:            {literal: true},
+	BooleanLiteral:           {literal: true},
+	NullLiteral:              {literal: true},
+}
+
+// NodeError reports a malformed node in a JSON AST. Path is a JSON pointer
+// (RFC 6901) to the node, such as "/Left/Arguments/0"; it is empty for the
+// root node.
+type NodeError struct {
+	Path    string
+	Message string
+}
+
+// Error returns the message prefixed with the location of the node.
+func (e *NodeError) Error() string {
+	if e.Path == "" {
+		return "root node: " + e.Message
+	}
+	return "node " + e.Path + ": " + e.Message
+}
+
+// jsonNode mirrors the JSON layout of a Node, so a whole AST is decoded in
+// a single pass before its nodes are checked.
+type jsonNode struct {
+	Type      string
+	Value     string
+	Left      *jsonNode
+	Right     *jsonNode
+	Elements  []*jsonNode
+	Arguments []*jsonNode
+	Start     int
+	End       int
+}
+
+// UnmarshalJSON decodes a node and its children, checking that every node
+// has a known type, the operands its type requires and nothing else. List
+// sets and LIKE / MATCHES patterns are rebuilt as the parser would. Errors
+// are *NodeError values pointing at the malformed node.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var raw *jsonNode
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return decodeError(err)
+	}
+	if raw == nil {
+		return nil
+	}
+
+	node, err := raw.node("")
+	if err != nil {
+		return err
+	}
+	*n = *node
+	return nil
+}
+
+// node converts a decoded JSON node at the given path into a Node.
+func (r *jsonNode) node(path string) (*Node, error) {
+	if r == nil {
+		return nil, &NodeError{Path: path, Message: "missing node"}
+	}
+
+	kind, ok := ParseNodeKind(r.Type)
+	if !ok {
+		if r.Type == "" {
+			return nil, &NodeError{Path: path, Message: "missing node type"}
+		}
+		return nil, &NodeError{Path: path, Message: fmt.Sprintf("unknown node type: %s", r.Type)}
+	}
+
+	node := &Node{Type: kind, Value: r.Value}
+	var err error
+	if r.Left != nil {
+		if node.Left, err = r.Left.node(path + "/Left"); err != nil {
+			return nil, err
+		}
+	}
+	if r.Right != nil {
+		if node.Right, err = r.Right.node(path + "/Right"); err != nil {
+			return nil, err
+		}
+	}
+	if node.Elements, err = nodeList(r.Elements, path+"/Elements"); err != nil {
+		return nil, err
+	}
+	if node.Arguments, err = nodeList(r.Arguments, path+"/Arguments"); err != nil {
+		return nil, err
+	}
+
+	if message := checkShape(node); message != "" {
+		return nil, &NodeError{Path: path, Message: message}
+	}
+
+	// Rebuild the values the parser derives at parse time
+	switch {
+	case kind == ListLiteral:
+		node = NewListLiteral(node.Elements)
+	case kind == BinaryExpression && (node.Value == "LIKE" || node.Value == "MATCHES"):
+		if node, err = NewPatternExpression(node.Value, node.Left, node.Right); err != nil {
+			return nil, &NodeError{Path: path, Message: err.Error()}
+		}
+	}
+
+	node.Start, node.End = r.Start, r.End
+	return node, nil
+}
+
+// nodeList converts the decoded Elements or Arguments of a node.
+func nodeList(raw []*jsonNode, path string) ([]*Node, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	nodes := make([]*Node, 0, len(raw))
+	for i, item := range raw {
+		node, err := item.node(path + "/" + strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// checkShape verifies that a node has the operands and Value its kind
+// requires and no others. It returns a description of the problem, or ""
+// if the node is well formed.
+func checkShape(node *Node) string {
+	shape, ok := shapes[node.Type]
+	if !ok {
+		return fmt.Sprintf("unknown node type: %s", node.Type)
+	}
+
+	switch {
+	case shape.left && node.Left == nil:
+		return fmt.Sprintf("%s requires Left", node.Type)
+	case !shape.left && node.Left != nil:
+		return fmt.Sprintf("%s does not take Left", node.Type)
+	case shape.right && node.Right == nil:
+		return fmt.Sprintf("%s requires Right", node.Type)
+	case !shape.right && node.Right != nil:
+		return fmt.Sprintf("%s does not take Right", node.Type)
+	case !shape.elements && len(node.Elements) > 0:
+		return fmt.Sprintf("%s does not take Elements", node.Type)
+	case !shape.arguments && len(node.Arguments) > 0:
+		return fmt.Sprintf("%s does not take Arguments", node.Type)
+	case shape.operator && node.Value == "":
+		return fmt.Sprintf("%s requires a Value", node.Type)
+	case shape.literal && !validLiteral(node):
+		return fmt.Sprintf("invalid %s: %q", node.Type, node.Value)
+	}
+	return ""
+}
+
+// validLiteral reports whether a literal node holds a value of its type.
+func validLiteral(node *Node) bool {
+	switch node.Type {
+	case BooleanLiteral:
+		return node.Value == "true" || node.Value == "false"
+	case NullLiteral:
+		return node.Value == "null"
+	}
+	_, ok := node.LiteralValue()
+	return ok
+}
+
+// decodeError turns a JSON decoding error into a NodeError, using the
+// field path of type errors ("Left.Arguments.1.Value") to find the node.
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return &NodeError{Message: err.Error()}
+	}
+
+	fields := strings.Split(typeErr.Field, ".")
+	message := fmt.Sprintf("node must be an object, got a JSON %s", typeErr.Value)
+	switch last := fields[len(fields)-1]; last {
+	case "Type", "Value", "Start", "End":
+		// A scalar field has the wrong type, so point at its node
+		fields = fields[:len(fields)-1]
+		message = fmt.Sprintf("%s cannot be a JSON %s", last, typeErr.Value)
+	case "Elements", "Arguments":
+		message = fmt.Sprintf("%s must be an array, got a JSON %s", last, typeErr.Value)
+	}
+
+	path := ""
+	for _, field := range fields {
+		path += "/" + field
+	}
+	return &NodeError{Path: path, Message: message}
+}
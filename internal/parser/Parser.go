@@ -1,21 +1,22 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
-// Node represents a node in the Abstract Syntax Tree.
-type Node struct {
-	Type  string
-	Value string
-	Left  *Node
-	Right *Node
-}
-
-// Parser holds the tokenizer and the current token being processed.
+// Parser holds the tokenizer, the current token being processed and the
+// diagnostics collected so far.
 type Parser struct {
-	tokenizer *Tokenizer
-	lookahead *Token
+	tokenizer   *Tokenizer
+	lookahead   *Token
+	previous    *Token
+	diagnostics Diagnostics
+
+	// closers is a stack of the tokens that end the construct currently being
+	// parsed, such as ")" inside parentheses. Error recovery stops at them.
+	closers []string
 }
 
 // NewParser creates a new Parser instance with the given tokenizer.
@@ -25,18 +26,38 @@ func NewParser(tokenizer *Tokenizer) *Parser {
 	}
 }
 
-// ParseRule parses the entire rule and returns the AST or an error.
+// ParseRule parses the entire rule and returns the AST. When the rule has
+// errors, parsing recovers after each one and the error returned is a
+// Diagnostics value listing every problem found.
 func (p *Parser) ParseRule() (*Node, error) {
+	p.diagnostics = nil
 	p.lookahead = p.tokenizer.GetNextToken()
 
 	// Handle the case where the input is empty
 	if p.lookahead == nil {
-		return nil, fmt.Errorf("parsing error: input is empty. Please provide a valid rule")
+		return nil, Diagnostics{p.diagnosticAt(0, 0, CodeEmptyInput, "input is empty. Please provide a valid rule", "")}
 	}
 
 	ast, err := p.Construct()
 	if err != nil {
-		return nil, err
+		p.report(err)
+	}
+
+	// Anything left over does not belong to the rule
+	for p.lookahead != nil {
+		p.report(p.unexpected(p.lookahead, "end of rule", CodeTrailingInput))
+		p.advance()
+		p.synchronize()
+
+		// Keep checking the rest of the rule for further problems
+		if p.lookahead != nil && (p.lookahead.Type == "LOGICAL_AND" || p.lookahead.Type == "LOGICAL_OR") {
+			p.advance()
+			p.Construct()
+		}
+	}
+
+	if len(p.diagnostics) > 0 {
+		return nil, p.diagnostics
 	}
 
 	return ast, nil
@@ -60,48 +81,73 @@ func (p *Parser) LogicalOrExpression() (*Node, error) {
 		}
 		right, err := p.LogicalAndExpression()
 		if err != nil {
-			return nil, fmt.Errorf("logical OR expression error while parsing right side: %s", err)
+			return nil, fmt.Errorf("logical OR expression error while parsing right side: %w", err)
 		}
 
-		left = &Node{
-			Type:  "LogicalOrExpression",
+		left = spanning(&Node{
+			Type:  LogicalOrExpression,
 			Value: operator.Value,
 			Left:  left,
 			Right: right,
-		}
+		}, left, right)
 	}
 
 	return left, nil
 }
 
-// LogicalAndExpression processes logical AND expressions.
+// LogicalAndExpression processes logical AND expressions. Each operand is
+// a recovery point: an error inside it is recorded and parsing resumes at
+// the next AND / OR so later problems are reported too.
 func (p *Parser) LogicalAndExpression() (*Node, error) {
-	left, err := p.EqualityExpression()
-	if err != nil {
-		return nil, err
-	}
+	left := p.recoverable(p.UnaryExpression)
 
 	for p.lookahead != nil && p.lookahead.Type == "LOGICAL_AND" {
 		operator, err := p.eat("LOGICAL_AND")
 		if err != nil {
 			return nil, fmt.Errorf("expected 'AND' operator, but got: %s", p.lookahead.Value)
 		}
-		right, err := p.EqualityExpression()
-		if err != nil {
-			return nil, fmt.Errorf("logical AND expression error while parsing right side: %s", err)
-		}
+		right := p.recoverable(p.UnaryExpression)
 
-		left = &Node{
-			Type:  "LogicalAndExpression",
+		left = spanning(&Node{
+			Type:  LogicalAndExpression,
 			Value: operator.Value,
 			Left:  left,
 			Right: right,
-		}
+		}, left, right)
 	}
 
 	return left, nil
 }
 
+// UnaryExpression processes logical negation (NOT / !).
+// It binds looser than comparisons, so NOT age > 30 negates the whole comparison.
+func (p *Parser) UnaryExpression() (*Node, error) {
+	if p.lookahead == nil || p.lookahead.Type != "LOGICAL_NOT" {
+		return p.EqualityExpression()
+	}
+
+	operator, err := p.eat("LOGICAL_NOT")
+	if err != nil {
+		return nil, fmt.Errorf("expected 'NOT' operator, but got: %s", p.lookahead.Value)
+	}
+
+	argument, err := p.UnaryExpression()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operand of unary expression: %w", err)
+	}
+	if argument == nil {
+		return nil, p.diagnosticAt(operator.Start, operator.End, CodeUnexpectedToken, fmt.Sprintf("'%s' is missing its operand", operator.Value), "")
+	}
+
+	return &Node{
+		Type:  UnaryExpression,
+		Value: operator.Value,
+		Left:  argument,
+		Start: operator.Start,
+		End:   argument.End,
+	}, nil
+}
+
 // EqualityExpression processes equality expressions.
 func (p *Parser) EqualityExpression() (*Node, error) {
 	left, err := p.RelationalExpression()
@@ -120,12 +166,12 @@ func (p *Parser) EqualityExpression() (*Node, error) {
 			return nil, fmt.Errorf("failed to parse right side of equality expression: %w", err)
 		}
 
-		left = &Node{
-			Type:  "BinaryExpression",
+		left = spanning(&Node{
+			Type:  BinaryExpression,
 			Value: operator.Value,
 			Left:  left,
 			Right: right,
-		}
+		}, left, right)
 	}
 
 	return left, nil
@@ -133,37 +179,153 @@ func (p *Parser) EqualityExpression() (*Node, error) {
 
 // RelationalExpression processes relational expressions.
 func (p *Parser) RelationalExpression() (*Node, error) {
-	left, err := p.PrimaryExpression()
+	left, err := p.AdditiveExpression()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.lookahead != nil && p.lookahead.Type == "RELATIONAL_OPERATOR" {
-		operator, err := p.eat("RELATIONAL_OPERATOR")
+	for p.lookahead != nil && (p.lookahead.Type == "RELATIONAL_OPERATOR" || p.lookahead.Type == "IN_OPERATOR" || p.lookahead.Type == "STRING_OPERATOR") {
+		operator, err := p.eat(p.lookahead.Type)
 		if err != nil {
-			return nil, fmt.Errorf("expected '>,<,>=,<=' but got: %s", p.lookahead.Value)
+			return nil, fmt.Errorf("expected '>,<,>=,<=,IN,LIKE,...' but got: %s", p.lookahead.Value)
 		}
 
-		right, err := p.PrimaryExpression()
+		rightStart := p.lookahead
+		right, err := p.AdditiveExpression()
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse right side of relational expression: %w", err)
 		}
 
-		left = &Node{
-			Type:  "BinaryExpression",
+		value := operator.Value
+		if operator.Type == "IN_OPERATOR" {
+			// Normalize "not  in" and friends to "IN" / "NOT IN"
+			value = strings.ToUpper(strings.Join(strings.Fields(value), " "))
+		}
+
+		if value == "LIKE" || value == "MATCHES" {
+			pattern, err := NewPatternExpression(value, left, right)
+			if err != nil {
+				return nil, p.diagnosticAt(rightStart.Start, p.previous.End, CodeInvalidPattern, err.Error(), "")
+			}
+			left = spanning(pattern, left, right)
+			continue
+		}
+
+		left = spanning(&Node{
+			Type:  BinaryExpression,
+			Value: value,
+			Left:  left,
+			Right: right,
+		}, left, right)
+	}
+
+	return left, nil
+}
+
+// AdditiveExpression processes addition and subtraction.
+func (p *Parser) AdditiveExpression() (*Node, error) {
+	left, err := p.MultiplicativeExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.lookahead != nil && p.lookahead.Type == "ADDITIVE_OPERATOR" {
+		operator, err := p.eat("ADDITIVE_OPERATOR")
+		if err != nil {
+			return nil, fmt.Errorf("expected '+,-' but got: %s", p.lookahead.Value)
+		}
+
+		right, err := p.MultiplicativeExpression()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse right side of additive expression: %w", err)
+		}
+
+		left = spanning(&Node{
+			Type:  AdditiveExpression,
 			Value: operator.Value,
 			Left:  left,
 			Right: right,
+		}, left, right)
+	}
+
+	return left, nil
+}
+
+// MultiplicativeExpression processes multiplication, division and modulo.
+func (p *Parser) MultiplicativeExpression() (*Node, error) {
+	left, err := p.PrefixExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.lookahead != nil && (p.lookahead.Type == "MULTIPLICATIVE_OPERATOR" || p.lookahead.Type == "MODULO_OPERATOR") {
+		operator, err := p.eat(p.lookahead.Type)
+		if err != nil {
+			return nil, fmt.Errorf("expected '*,/,%%' but got: %s", p.lookahead.Value)
+		}
+
+		right, err := p.PrefixExpression()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse right side of multiplicative expression: %w", err)
 		}
+
+		left = spanning(&Node{
+			Type:  MultiplicativeExpression,
+			Value: operator.Value,
+			Left:  left,
+			Right: right,
+		}, left, right)
 	}
 
 	return left, nil
 }
 
+// PrefixExpression processes a leading '+' or '-'. A sign directly before a
+// number is folded into a signed NumericLiteral, otherwise it becomes a
+// UnaryExpression negating the operand.
+func (p *Parser) PrefixExpression() (*Node, error) {
+	if p.lookahead == nil || p.lookahead.Type != "ADDITIVE_OPERATOR" {
+		return p.PrimaryExpression()
+	}
+
+	operator, err := p.eat("ADDITIVE_OPERATOR")
+	if err != nil {
+		return nil, fmt.Errorf("expected '+,-' but got: %s", p.lookahead.Value)
+	}
+
+	if p.lookahead != nil && p.lookahead.Type == "NUMBER" {
+		literal, err := p.NumericLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if operator.Value == "-" {
+			literal.Value = "-" + literal.Value
+		}
+		literal.Start = operator.Start
+		return literal, nil
+	}
+
+	argument, err := p.PrefixExpression()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operand of '%s': %w", operator.Value, err)
+	}
+	if argument == nil {
+		return nil, p.diagnosticAt(operator.Start, operator.End, CodeUnexpectedToken, fmt.Sprintf("'%s' is missing its operand", operator.Value), "")
+	}
+
+	return &Node{
+		Type:  UnaryExpression,
+		Value: operator.Value,
+		Left:  argument,
+		Start: operator.Start,
+		End:   argument.End,
+	}, nil
+}
+
 // PrimaryExpression processes primary expressions.
 func (p *Parser) PrimaryExpression() (*Node, error) {
 	if p.lookahead == nil {
-		return nil, fmt.Errorf("unexpected end of input in PrimaryExpression expected an Identifier or '(,)'")
+		return nil, p.unexpected(nil, "an identifier, literal or '('", CodeUnexpectedEnd)
 	}
 
 	if p.isLiteral(p.lookahead.Type) {
@@ -172,34 +334,179 @@ func (p *Parser) PrimaryExpression() (*Node, error) {
 
 	switch p.lookahead.Type {
 	case "IDENTIFIER":
-		return p.Identifier()
+		return p.MemberExpression()
 	case "(":
 		return p.ParenthesizedExpression()
+	case "[":
+		return p.ListLiteral()
 	default:
-		return nil, fmt.Errorf("unexpected token '%s' in PrimaryExpression; expected IDENTIFIER, '(', or a literal", p.lookahead.Value)
+		return nil, p.unexpected(p.lookahead, "an identifier, literal or '('", CodeUnexpectedToken)
 	}
 }
 
 // ParenthesizedExpression processes expressions enclosed in parentheses.
+// The span of the returned expression is widened to include the parentheses.
 func (p *Parser) ParenthesizedExpression() (*Node, error) {
-	_, err := p.eat("(")
+	open, err := p.eat("(")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse ParenthesizedExpression: %w", err)
 	}
 
+	p.closers = append(p.closers, ")")
 	exp, err := p.LogicalOrExpression()
+	p.closers = p.closers[:len(p.closers)-1]
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse expression inside parentheses: %w", err)
 	}
 
-	_, err = p.eat(")")
+	closing, err := p.eat(")")
 	if err != nil {
 		return nil, fmt.Errorf("failed to find closing parenthesis in ParenthesizedExpression: %w", err)
 	}
 
+	if exp == nil {
+		// Parsing recovered from an error inside the parentheses, which was
+		// already recorded; report it again so the caller stops too
+		if n := len(p.diagnostics); n > 0 {
+			return nil, p.diagnostics[n-1]
+		}
+		return nil, p.diagnosticAt(open.Start, closing.End, CodeUnexpectedToken, "expected an expression inside parentheses", "")
+	}
+
+	exp.Start, exp.End = open.Start, closing.End
 	return exp, nil
 }
 
+// MemberExpression processes attribute paths into nested data, such as
+// user.address.country or orders[0].total. Dotted access stores the property
+// Identifier in Right with Value ".", indexed access stores the index
+// expression in Right with Value "[]".
+func (p *Parser) MemberExpression() (*Node, error) {
+	object, err := p.Identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.lookahead != nil && p.lookahead.Type == "(" {
+		object, err = p.CallExpression(object.Value, object.Start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for p.lookahead != nil && (p.lookahead.Type == "." || p.lookahead.Type == "[") {
+		if p.lookahead.Type == "." {
+			p.eat(".")
+			property, err := p.Identifier()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse property name after '.': %w", err)
+			}
+
+			object = spanning(&Node{
+				Type:  MemberExpression,
+				Value: ".",
+				Left:  object,
+				Right: property,
+			}, object, property)
+			continue
+		}
+
+		p.eat("[")
+		p.closers = append(p.closers, "]")
+		index, err := p.LogicalOrExpression()
+		p.closers = p.closers[:len(p.closers)-1]
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse index expression: %w", err)
+		}
+
+		_, err = p.eat("]")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find closing bracket in MemberExpression: %w", err)
+		}
+
+		object = &Node{
+			Type:  MemberExpression,
+			Value: "[]",
+			Left:  object,
+			Right: index,
+			Start: object.Start,
+			End:   p.previous.End,
+		}
+	}
+
+	return object, nil
+}
+
+// CallExpression processes the argument list of a function call such as
+// lower(name). The function name has already been consumed as an Identifier
+// starting at offset start.
+func (p *Parser) CallExpression(name string, start int) (*Node, error) {
+	_, err := p.eat("(")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CallExpression: %w", err)
+	}
+
+	var arguments []*Node
+	for p.lookahead != nil && p.lookahead.Type != ")" {
+		p.closers = append(p.closers, ",)")
+		argument, err := p.LogicalOrExpression()
+		p.closers = p.closers[:len(p.closers)-1]
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse argument %d of %s(): %w", len(arguments)+1, name, err)
+		}
+		arguments = append(arguments, argument)
+
+		if p.lookahead == nil || p.lookahead.Type != "," {
+			break
+		}
+		p.eat(",")
+	}
+
+	_, err = p.eat(")")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find closing parenthesis in call to %s(): %w", name, err)
+	}
+
+	return &Node{
+		Type:      CallExpression,
+		Value:     name,
+		Arguments: arguments,
+		Start:     start,
+		End:       p.previous.End,
+	}, nil
+}
+
+// ListLiteral processes bracketed, comma separated lists such as ['US', 'CA'].
+func (p *Parser) ListLiteral() (*Node, error) {
+	open, err := p.eat("[")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ListLiteral: %w", err)
+	}
+
+	var elements []*Node
+	for p.lookahead != nil && p.lookahead.Type != "]" {
+		element, err := p.AdditiveExpression()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse list element: %w", err)
+		}
+		elements = append(elements, element)
+
+		if p.lookahead == nil || p.lookahead.Type != "," {
+			break
+		}
+		p.eat(",")
+	}
+
+	_, err = p.eat("]")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find closing bracket in ListLiteral: %w", err)
+	}
+
+	list := NewListLiteral(elements)
+	list.Start, list.End = open.Start, p.previous.End
+	return list, nil
+}
+
 // Identifier processes identifier tokens.
 func (p *Parser) Identifier() (*Node, error) {
 	name, err := p.eat("IDENTIFIER")
@@ -208,14 +515,17 @@ func (p *Parser) Identifier() (*Node, error) {
 	}
 
 	return &Node{
-		Type:  "Identifier",
+		Type:  Identifier,
 		Value: name.Value,
+		Start: name.Start,
+		End:   name.End,
 	}, nil
 }
 
 // isLiteral checks if the token type is a literal.
 func (p *Parser) isLiteral(tokenType string) bool {
 	return tokenType == "NUMBER" ||
+		tokenType == "DURATION" ||
 		tokenType == "STRING" ||
 		tokenType == "true" ||
 		tokenType == "false" ||
@@ -231,6 +541,8 @@ func (p *Parser) Literal() (*Node, error) {
 	switch p.lookahead.Type {
 	case "NUMBER":
 		return p.NumericLiteral()
+	case "DURATION":
+		return p.DurationLiteral()
 	case "STRING":
 		return p.StringLiteral()
 	case "true":
@@ -240,7 +552,7 @@ func (p *Parser) Literal() (*Node, error) {
 	case "null":
 		return p.NullLiteral()
 	default:
-		return nil, fmt.Errorf("unexpected literal type '%s' encountered", p.lookahead.Type)
+		return nil, p.unexpected(p.lookahead, "a literal", CodeUnexpectedToken)
 	}
 }
 
@@ -252,8 +564,29 @@ func (p *Parser) NumericLiteral() (*Node, error) {
 	}
 
 	return &Node{
-		Type:  "NumericLiteral",
+		Type:  NumericLiteral,
+		Value: token.Value,
+		Start: token.Start,
+		End:   token.End,
+	}, nil
+}
+
+// DurationLiteral processes duration literals such as 30d or 1h30m.
+func (p *Parser) DurationLiteral() (*Node, error) {
+	token, err := p.eat("DURATION")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DurationLiteral: %w", err)
+	}
+
+	if _, err := ParseDuration(token.Value); err != nil {
+		return nil, p.diagnosticAt(token.Start, token.End, CodeInvalidLiteral, err.Error(), "")
+	}
+
+	return &Node{
+		Type:  DurationLiteral,
 		Value: token.Value,
+		Start: token.Start,
+		End:   token.End,
 	}, nil
 }
 
@@ -265,8 +598,10 @@ func (p *Parser) StringLiteral() (*Node, error) {
 	}
 
 	return &Node{
-		Type:  "StringLiteral",
+		Type:  StringLiteral,
 		Value: token.Value,
+		Start: token.Start,
+		End:   token.End,
 	}, nil
 }
 
@@ -278,36 +613,147 @@ func (p *Parser) BooleanLiteral(value string) (*Node, error) {
 	}
 
 	return &Node{
-		Type:  "BooleanLiteral",
+		Type:  BooleanLiteral,
 		Value: token.Value,
+		Start: token.Start,
+		End:   token.End,
 	}, nil
 }
 
 // NullLiteral processes null literals.
 func (p *Parser) NullLiteral() (*Node, error) {
-	_, err := p.eat("null")
+	token, err := p.eat("null")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse NullLiteral: %w", err)
 	}
 
 	return &Node{
-		Type:  "NullLiteral",
+		Type:  NullLiteral,
 		Value: "null",
+		Start: token.Start,
+		End:   token.End,
 	}, nil
 }
 
+// spanning sets the span of node to run from the start of first to the end
+// of last. Either may be nil while recovering from a parse error.
+func spanning(node, first, last *Node) *Node {
+	if first != nil {
+		node.Start = first.Start
+	}
+	if last != nil {
+		node.End = last.End
+	}
+	return node
+}
+
 // eat consumes the current token if it matches the expected type and returns it.
 func (p *Parser) eat(tokenType string) (*Token, error) {
 	token := p.lookahead
 	if token == nil {
-		return nil, fmt.Errorf("unexpected end of input, expected: %s", tokenType)
+		return nil, p.unexpected(nil, "'"+tokenType+"'", CodeUnexpectedEnd)
 	}
 
 	if token.Type != tokenType {
-		return nil, fmt.Errorf("unexpected token: '%s', expected: '%s'", token.Value, tokenType)
+		return nil, p.unexpected(token, "'"+tokenType+"'", CodeUnexpectedToken)
 	}
 
 	// Move to the next token.
-	p.lookahead = p.tokenizer.GetNextToken()
+	p.advance()
 	return token, nil
 }
+
+// advance moves to the next token.
+func (p *Parser) advance() {
+	p.previous = p.lookahead
+	p.lookahead = p.tokenizer.GetNextToken()
+}
+
+// unexpected creates the diagnostic for finding token (nil at the end of the
+// input) where expected was required. Illegal characters and unterminated
+// strings get their own codes whatever the parser was expecting.
+func (p *Parser) unexpected(token *Token, expected string, code string) *Diagnostic {
+	suggestion := suggestionFor(token, expected)
+
+	if token == nil {
+		end := len(p.tokenizer.input)
+		return p.diagnosticAt(end, end, CodeUnexpectedEnd, fmt.Sprintf("unexpected end of input, expected: %s", expected), suggestion)
+	}
+
+	switch token.Type {
+	case "ILLEGAL":
+		return p.diagnosticAt(token.Start, token.End, CodeIllegalCharacter, fmt.Sprintf("illegal character '%s'", token.Value), suggestion)
+	case "UNTERMINATED_STRING":
+		return p.diagnosticAt(token.Start, token.End, CodeUnterminatedString, "unterminated string", suggestion)
+	}
+
+	return p.diagnosticAt(token.Start, token.End, code, fmt.Sprintf("unexpected token: '%s', expected: %s", token.Value, expected), suggestion)
+}
+
+// diagnosticAt creates a diagnostic for the byte span [start, end).
+func (p *Parser) diagnosticAt(start, end int, code, message, suggestion string) *Diagnostic {
+	return NewDiagnostic(p.tokenizer.input, start, end, code, message, suggestion)
+}
+
+// report records the diagnostic carried by err. Errors that are not
+// diagnostics are positioned at the current token.
+func (p *Parser) report(err error) {
+	var diagnostic *Diagnostic
+	if !errors.As(err, &diagnostic) {
+		start, end := len(p.tokenizer.input), len(p.tokenizer.input)
+		if p.lookahead != nil {
+			start, end = p.lookahead.Start, p.lookahead.End
+		}
+		diagnostic = p.diagnosticAt(start, end, CodeUnexpectedToken, err.Error(), "")
+	}
+
+	// A single mistake can surface at several levels; report it once
+	if n := len(p.diagnostics); n > 0 && p.diagnostics[n-1].Start == diagnostic.Start {
+		return
+	}
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// recoverable runs parse and, if it fails, records the error and skips
+// ahead to a point where parsing can resume. It returns nil on error.
+func (p *Parser) recoverable(parse func() (*Node, error)) *Node {
+	node, err := parse()
+	if err != nil {
+		p.report(err)
+		p.synchronize()
+		return nil
+	}
+	return node
+}
+
+// synchronize skips tokens until the next AND / OR or a token that closes
+// the enclosing construct, keeping nested brackets balanced.
+func (p *Parser) synchronize() {
+	depth := 0
+	for p.lookahead != nil {
+		switch p.lookahead.Type {
+		case "(", "[":
+			depth++
+		case ")", "]", ",":
+			if depth == 0 && p.isCloser(p.lookahead.Type) {
+				return
+			}
+			if depth > 0 && p.lookahead.Type != "," {
+				depth--
+			}
+		case "LOGICAL_AND", "LOGICAL_OR":
+			if depth == 0 {
+				return
+			}
+		}
+		p.advance()
+	}
+}
+
+// isCloser reports whether tokenType ends the construct being parsed.
+func (p *Parser) isCloser(tokenType string) bool {
+	if len(p.closers) == 0 {
+		return false
+	}
+	return strings.Contains(p.closers[len(p.closers)-1], tokenType)
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// MaxPatternLength is the longest LIKE / MATCHES pattern a rule may contain.
+const MaxPatternLength = 1024
+
+// maxPatternInstructions bounds the size of a compiled pattern. Go's RE2
+// engine already matches in linear time, so there is no catastrophic
+// backtracking, but nested repetitions can still blow up the program size.
+const maxPatternInstructions = 10000
+
+// NewPatternExpression creates a LIKE or MATCHES BinaryExpression and
+// compiles its pattern once, so it is validated when the rule is created
+// instead of on every evaluation. The pattern must be a string literal.
+func NewPatternExpression(operator string, left, right *Node) (*Node, error) {
+	if right == nil || right.Type != StringLiteral {
+		return nil, fmt.Errorf("right side of %s must be a string literal pattern", operator)
+	}
+
+	pattern, _ := right.LiteralValue()
+	source := pattern.(string)
+	if operator == "LIKE" {
+		source = likeToRegexp(source)
+	}
+
+	compiled, err := compilePattern(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern %s: %w", operator, right.Value, err)
+	}
+
+	return &Node{
+		Type:    BinaryExpression,
+		Value:   operator,
+		Left:    left,
+		Right:   right,
+		Pattern: compiled,
+	}, nil
+}
+
+// compilePattern compiles a regular expression after checking it stays
+// within the length and program size limits.
+func compilePattern(source string) (*regexp.Regexp, error) {
+	if len(source) > MaxPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d characters", MaxPatternLength)
+	}
+
+	parsed, err := syntax.Parse(source, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	program, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(program.Inst) > maxPatternInstructions {
+		return nil, fmt.Errorf("pattern is too complex")
+	}
+
+	return regexp.Compile(source)
+}
+
+// likeToRegexp translates a SQL LIKE pattern into an anchored regular
+// expression: '%' matches any run of characters, '_' matches exactly one,
+// and a backslash escapes the next character.
+func likeToRegexp(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString(`(?s)^`)
+
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			builder.WriteString(`.*`)
+		case r == '_':
+			builder.WriteString(`.`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		builder.WriteString(`\\`)
+	}
+
+	builder.WriteString(`$`)
+	return builder.String()
+}
@@ -2,12 +2,15 @@ package parser
 
 import (
 	"regexp"
+	"unicode/utf8"
 )
 
-// Token represents a token with a type and value.
+// Token represents a token with a type, value and its byte offsets in the input.
 type Token struct {
 	Type  string
 	Value string
+	Start int
+	End   int
 }
 
 // Spec defines the regular expressions for tokens and corresponding types.
@@ -35,6 +38,9 @@ var Spec = []struct {
 	{regexp.MustCompile(`^\?`), "?"},
 	{regexp.MustCompile(`^:`), ":"},
 
+	// Inequality must be matched before '!' and the relational operators
+	{regexp.MustCompile(`^(!=|<>)`), "EQUALITY_OPERATOR"},
+
 	// Relational operators
 	{regexp.MustCompile(`^[<>]=?`), "RELATIONAL_OPERATOR"},
 	{regexp.MustCompile(`^=`), "EQUALITY_OPERATOR"},
@@ -45,15 +51,23 @@ var Spec = []struct {
 	{regexp.MustCompile(`^!`), "LOGICAL_NOT"},
 	{regexp.MustCompile(`^\bAND\b`), "LOGICAL_AND"},
 	{regexp.MustCompile(`^\bOR\b`), "LOGICAL_OR"},
+	{regexp.MustCompile(`^\bNOT\s+IN\b`), "IN_OPERATOR"},
+	{regexp.MustCompile(`^\bIN\b`), "IN_OPERATOR"},
 	{regexp.MustCompile(`^\bNOT\b`), "LOGICAL_NOT"},
 
+	// String operators
+	{regexp.MustCompile(`^\b(CONTAINS|STARTS_WITH|ENDS_WITH|LIKE|MATCHES)\b`), "STRING_OPERATOR"},
+
 	// Math operators
 	{regexp.MustCompile(`^[+\-]`), "ADDITIVE_OPERATOR"},
 	{regexp.MustCompile(`^[*\/]`), "MULTIPLICATIVE_OPERATOR"},
 	{regexp.MustCompile(`^%`), "MODULO_OPERATOR"},
 
-	// Numbers
-	{regexp.MustCompile(`^\d+`), "NUMBER"},
+	// Durations: 30d, 12h, 1h30m, 500ms (must be matched before plain numbers)
+	{regexp.MustCompile(`^(\d+(\.\d+)?(ms|s|m|h|d|w))+\b`), "DURATION"},
+
+	// Numbers: integers, decimals and exponents (signs are parsed as prefix operators)
+	{regexp.MustCompile(`^\d+(\.\d+)?([eE][+\-]?\d+)?`), "NUMBER"},
 
 	// Double quoted String
 	{regexp.MustCompile(`^"[^"]*"`), "STRING"},
@@ -61,6 +75,15 @@ var Spec = []struct {
 	// Single quoted String
 	{regexp.MustCompile(`^'[^']*'`), "STRING"},
 
+	// Strings missing their closing quote run to the end of the input
+	{regexp.MustCompile(`^"[^"]*`), "UNTERMINATED_STRING"},
+	{regexp.MustCompile(`^'[^']*`), "UNTERMINATED_STRING"},
+
+	// Boolean and null literals
+	{regexp.MustCompile(`^\btrue\b`), "true"},
+	{regexp.MustCompile(`^\bfalse\b`), "false"},
+	{regexp.MustCompile(`^\bnull\b`), "null"},
+
 	// Identifier
 	{regexp.MustCompile(`^\w+`), "IDENTIFIER"},
 }
@@ -84,7 +107,9 @@ func (t *Tokenizer) hasMoreTokens() bool {
 	return t.cursor < len(t.input)
 }
 
-// GetNextToken extracts the next token from the input string.
+// GetNextToken extracts the next token from the input string. A character
+// that starts no token is returned as an ILLEGAL token so the parser can
+// report it instead of silently stopping.
 func (t *Tokenizer) GetNextToken() *Token {
 	if !t.hasMoreTokens() {
 		return nil
@@ -94,6 +119,7 @@ func (t *Tokenizer) GetNextToken() *Token {
 
 	for _, spec := range Spec {
 		if matched := t.match(spec.Pattern, input); matched != "" {
+			start := t.cursor
 			t.cursor += len(matched)
 
 			if spec.TokenType == "" {
@@ -103,11 +129,46 @@ func (t *Tokenizer) GetNextToken() *Token {
 			return &Token{
 				Type:  spec.TokenType,
 				Value: matched,
+				Start: start,
+				End:   t.cursor,
 			}
 		}
 	}
 
-	return nil // No matching token found
+	// No matching token found
+	_, size := utf8.DecodeRuneInString(input)
+	start := t.cursor
+	t.cursor += size
+	return &Token{
+		Type:  "ILLEGAL",
+		Value: input[:size],
+		Start: start,
+		End:   t.cursor,
+	}
+}
+
+// Position converts a byte offset into a 1-based line and column, counting
+// columns in characters rather than bytes.
+func (t *Tokenizer) Position(offset int) (line, column int) {
+	return position(t.input, offset)
+}
+
+// position converts a byte offset in input into a 1-based line and column.
+func position(input string, offset int) (line, column int) {
+	if offset > len(input) {
+		offset = len(input)
+	}
+
+	line, column = 1, 1
+	for _, r := range input[:offset] {
+		if r == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
+	}
+	return line, column
 }
 
 // match tries to match the regular expression pattern at the start of the string.
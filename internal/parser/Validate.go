@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Limits bounds the size of ASTs accepted from clients.
+type Limits struct {
+	MaxDepth int
+	MaxNodes int
+}
+
+// DefaultLimits are the limits applied to ASTs sent to /evaluate_rule.
+var DefaultLimits = Limits{MaxDepth: 64, MaxNodes: 2000}
+
+// operators lists the Values accepted by each operator node kind.
+var operators = map[NodeKind][]string{
+	LogicalOrExpression:      {"OR", "||"},
+	LogicalAndExpression:     {"AND", "&&"},
+	UnaryExpression:          {"NOT", "!", "-", "+"},
+	BinaryExpression:         {"=", "!=", "<>", ">", "<", ">=", "<=", "IN", "NOT IN", "CONTAINS", "STARTS_WITH", "ENDS_WITH", "LIKE", "MATCHES"},
+	AdditiveExpression:       {"+", "-"},
+	MultiplicativeExpression: {"*", "/", "%"},
+	MemberExpression:         {".", "[]"},
+}
+
+// namePattern matches identifiers and function names, as the tokenizer does.
+var namePattern = regexp.MustCompile(`^\w+$`)
+
+// Validate checks an AST that did not come from the parser, such as one
+// sent by a client: every node must have a known type and the operands that
+// type requires, operators must be ones the parser produces, and the tree
+// must stay within the limits. Errors are *NodeError values with a JSON
+// pointer to the first bad node.
+func Validate(node *Node, limits Limits) error {
+	count := 0
+	return validate(node, "", 1, limits, &count)
+}
+
+// validate checks a node at the given path and depth, then its children.
+func validate(node *Node, path string, depth int, limits Limits, count *int) error {
+	if node == nil {
+		return &NodeError{Path: path, Message: "missing node"}
+	}
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return &NodeError{Path: path, Message: fmt.Sprintf("AST is nested deeper than %d levels", limits.MaxDepth)}
+	}
+	*count++
+	if limits.MaxNodes > 0 && *count > limits.MaxNodes {
+		return &NodeError{Path: path, Message: fmt.Sprintf("AST has more than %d nodes", limits.MaxNodes)}
+	}
+
+	if message := checkShape(node); message != "" {
+		return &NodeError{Path: path, Message: message}
+	}
+	if message := checkOperator(node); message != "" {
+		return &NodeError{Path: path, Message: message}
+	}
+
+	return forEachChild(node, path, func(child *Node, childPath string) error {
+		return validate(child, childPath, depth+1, limits, count)
+	})
+}
+
+// checkOperator verifies the Value of operator, identifier and call nodes.
+// It returns a description of the problem, or "" if the node is valid.
+func checkOperator(node *Node) string {
+	if allowed, ok := operators[node.Type]; ok && !slices.Contains(allowed, node.Value) {
+		return fmt.Sprintf("unknown %s operator: %q", node.Type, node.Value)
+	}
+
+	switch node.Type {
+	case Identifier:
+		if !namePattern.MatchString(node.Value) {
+			return fmt.Sprintf("invalid identifier: %q", node.Value)
+		}
+	case CallExpression:
+		if !namePattern.MatchString(node.Value) {
+			return fmt.Sprintf("invalid function name: %q", node.Value)
+		}
+	case MemberExpression:
+		if node.Value == "." && node.Right.Type != Identifier {
+			return "property access requires an Identifier on the Right"
+		}
+	case BinaryExpression:
+		if (node.Value == "LIKE" || node.Value == "MATCHES") && node.Pattern == nil {
+			return fmt.Sprintf("%s requires a string literal pattern", node.Value)
+		}
+	}
+	return ""
+}
+
+// forEachChild calls f with each child of the node and its JSON pointer,
+// stopping at the first error.
+func forEachChild(node *Node, path string, f func(child *Node, childPath string) error) error {
+	if node.Left != nil {
+		if err := f(node.Left, path+"/Left"); err != nil {
+			return err
+		}
+	}
+	if node.Right != nil {
+		if err := f(node.Right, path+"/Right"); err != nil {
+			return err
+		}
+	}
+	for i, element := range node.Elements {
+		if err := f(element, path+"/Elements/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	for i, argument := range node.Arguments {
+		if err := f(argument, path+"/Arguments/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PathOf returns the JSON pointer of target within the AST rooted at root.
+func PathOf(root, target *Node) (string, bool) {
+	if root == nil {
+		return "", false
+	}
+	if root == target {
+		return "", true
+	}
+
+	var found string
+	ok := false
+	forEachChild(root, "", func(child *Node, childPath string) error {
+		if path, inChild := PathOf(child, target); inChild {
+			found, ok = childPath+path, true
+			return errFound
+		}
+		return nil
+	})
+	return found, ok
+}
+
+// errFound stops forEachChild once PathOf has found its target.
+var errFound = errors.New("found")
@@ -0,0 +1,45 @@
+package parser
+
+import "time"
+
+// ValueSet is the hash set behind IN / NOT IN. It is built once when a
+// ListLiteral is created, so membership in lists of thousands of constants
+// stays O(1) per evaluation. Elements that are not constants (identifiers,
+// arithmetic, ...) are kept in Dynamic and evaluated against the context.
+type ValueSet struct {
+	values  map[interface{}]struct{}
+	Dynamic []*Node
+}
+
+// NewListLiteral creates a ListLiteral node and builds its ValueSet.
+func NewListLiteral(elements []*Node) *Node {
+	set := &ValueSet{values: make(map[interface{}]struct{}, len(elements))}
+	for _, element := range elements {
+		if value, ok := element.LiteralValue(); ok {
+			set.values[value] = struct{}{}
+			continue
+		}
+		set.Dynamic = append(set.Dynamic, element)
+	}
+
+	return &Node{
+		Type:     ListLiteral,
+		Value:    "[]",
+		Elements: elements,
+		Set:      set,
+	}
+}
+
+// Contains reports whether value is one of the constant elements. Numbers
+// must be passed as float64, which is how numeric literals are stored,
+// durations as time.Duration and null as nil.
+func (s *ValueSet) Contains(value interface{}) bool {
+	switch value.(type) {
+	case nil, float64, string, bool, time.Duration:
+		_, ok := s.values[value]
+		return ok
+	}
+
+	// Unhashable or unsupported values can never equal a constant element
+	return false
+}
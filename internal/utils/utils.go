@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
@@ -32,77 +33,22 @@ func PrintAST(node *parser.Node, depth int) {
 	}
 }
 
+// ConvertToASTNode converts an AST decoded from JSON back into parser nodes,
+// keeping the Start / End source spans when they are present. Malformed
+// nodes are reported as *parser.NodeError.
 func ConvertToASTNode(astJSON map[string]interface{}) (*parser.Node, error) {
-	nodeType := astJSON["Type"].(string)
-
-	switch nodeType {
-	case "LogicalOrExpression":
-		left, err := ConvertToASTNode(astJSON["Left"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		right, err := ConvertToASTNode(astJSON["Right"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-			Left:  left,
-			Right: right,
-		}, nil
-
-	case "LogicalAndExpression":
-		left, err := ConvertToASTNode(astJSON["Left"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		right, err := ConvertToASTNode(astJSON["Right"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-			Left:  left,
-			Right: right,
-		}, nil
-
-	case "BinaryExpression":
-		left, err := ConvertToASTNode(astJSON["Left"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		right, err := ConvertToASTNode(astJSON["Right"].(map[string]interface{}))
-		if err != nil {
-			return nil, err
-		}
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-			Left:  left,
-			Right: right,
-		}, nil
-
-	case "Identifier":
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-		}, nil
-
-	case "NumericLiteral":
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-		}, nil
+	if astJSON == nil {
+		return nil, &parser.NodeError{Message: "missing AST"}
+	}
 
-	case "StringLiteral":
-		return &parser.Node{
-			Type:  nodeType,
-			Value: astJSON["Value"].(string),
-		}, nil
+	data, err := json.Marshal(astJSON)
+	if err != nil {
+		return nil, err
+	}
 
-	default:
-		return nil, fmt.Errorf("unknown node type: %s", nodeType)
+	node := &parser.Node{}
+	if err := node.UnmarshalJSON(data); err != nil {
+		return nil, err
 	}
+	return node, nil
 }
@@ -0,0 +1,433 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Program is a rule compiled to bytecode. It is safe for concurrent use.
+type Program struct {
+	code      []byte
+	constants []value
+	// names holds the constants that are strings, for the instructions
+	// naming attributes
+	names []string
+	// lists are the list literals looked up by OpInSet, nodes the string
+	// comparisons evaluated by OpMatch
+	lists []*parser.Node
+	nodes []*parser.Node
+	calls []call
+	// stackSize is the deepest the stack gets
+	stackSize int
+	stacks    sync.Pool
+}
+
+// call is a function called by a program, with the number of arguments it
+// is called with.
+type call struct {
+	function  *interpreter.Function
+	arguments int
+}
+
+// Compile compiles the AST of a rule to bytecode. Functions are looked up in
+// interpreter.Functions when the rule is compiled, so functions registered
+// later are not seen. Node types and operators the parser never produces are
+// rejected instead of being evaluated as false.
+func Compile(node *parser.Node) (*Program, error) {
+	c := &compiler{program: &Program{}, constants: make(map[interface{}]int)}
+	if err := c.condition(node); err != nil {
+		return nil, err
+	}
+	if len(c.program.code) > math.MaxUint16 {
+		return nil, fmt.Errorf("rule is too large: %d bytes of bytecode, at most %d", len(c.program.code), math.MaxUint16)
+	}
+	// Operands index these tables with two bytes
+	for _, size := range []int{len(c.program.constants), len(c.program.lists), len(c.program.nodes), len(c.program.calls)} {
+		if size > math.MaxUint16 {
+			return nil, fmt.Errorf("rule is too large: %d constants, lists, comparisons or calls, at most %d", size, math.MaxUint16)
+		}
+	}
+
+	program := c.program
+	program.stackSize = c.maxDepth
+	program.stacks.New = func() interface{} {
+		stack := make([]value, program.stackSize)
+		return &stack
+	}
+	return program, nil
+}
+
+// compiler emits the bytecode of a program.
+type compiler struct {
+	program *Program
+	// constants maps constants to their index, so each is stored once
+	constants map[interface{}]int
+	// depth and maxDepth track the size of the stack
+	depth    int
+	maxDepth int
+}
+
+// comparisonOperators maps the comparison operators to their opcode.
+var comparisonOperators = map[string]Opcode{
+	"=":  OpEqual,
+	"!=": OpNotEqual,
+	"<>": OpNotEqual,
+	">":  OpGreater,
+	"<":  OpLess,
+	">=": OpGreaterEqual,
+	"<=": OpLessEqual,
+}
+
+// stringOperators holds the operators evaluated by OpMatch.
+var stringOperators = map[string]bool{
+	"CONTAINS":    true,
+	"STARTS_WITH": true,
+	"ENDS_WITH":   true,
+	"LIKE":        true,
+	"MATCHES":     true,
+}
+
+// arithmeticOperators maps the arithmetic operators to their opcode.
+var arithmeticOperators = map[string]Opcode{
+	"+": OpAdd,
+	"-": OpSubtract,
+	"*": OpMultiply,
+	"/": OpDivide,
+	"%": OpModulo,
+}
+
+// condition compiles a node evaluated as a condition, leaving a bool on the
+// stack, the counterpart of interpreter.Evaluate.
+func (c *compiler) condition(node *parser.Node) error {
+	if node == nil {
+		c.emit(OpFalse)
+		return nil
+	}
+
+	switch node.Type {
+	case parser.LogicalAndExpression, parser.LogicalOrExpression:
+		jump := OpJumpIfFalseOrPop
+		if node.Type == parser.LogicalOrExpression {
+			jump = OpJumpIfTrueOrPop
+		}
+		if err := c.condition(node.Left); err != nil {
+			return err
+		}
+		end := c.emitJump(jump)
+		if err := c.condition(node.Right); err != nil {
+			return err
+		}
+		c.patch(end)
+	case parser.UnaryExpression:
+		if node.Value == "-" || node.Value == "+" {
+			// Arithmetic sign used as a condition: true if it yields a value
+			if err := c.expression(node); err != nil {
+				return err
+			}
+			c.emit(OpPresent)
+			return nil
+		}
+		if err := c.condition(node.Left); err != nil {
+			return err
+		}
+		c.emit(OpNot)
+	case parser.BinaryExpression:
+		return c.comparison(node)
+	case parser.Identifier, parser.MemberExpression, parser.CallExpression:
+		if err := c.expression(node); err != nil {
+			return err
+		}
+		c.emit(OpTest)
+	case parser.BooleanLiteral:
+		if node.Value == "true" {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case parser.NullLiteral:
+		c.emit(OpFalse)
+	case parser.NumericLiteral, parser.StringLiteral:
+		// A literal used as a condition tests for an attribute of that name
+		c.emit(OpHas, c.constant(node.Value))
+	default:
+		return fmt.Errorf("unknown node type: %s", node.Type)
+	}
+	return nil
+}
+
+// comparison compiles a comparison, the counterpart of
+// evaluateBinaryExpression.
+func (c *compiler) comparison(node *parser.Node) error {
+	if node.Value == "IN" || node.Value == "NOT IN" {
+		return c.membership(node)
+	}
+
+	op, ok := comparisonOperators[node.Value]
+	if !ok && !stringOperators[node.Value] {
+		return fmt.Errorf("unknown comparison operator: %s", node.Value)
+	}
+	if err := c.expression(node.Left); err != nil {
+		return err
+	}
+	if err := c.expression(node.Right); err != nil {
+		return err
+	}
+
+	if isNullLiteral(node.Left) || isNullLiteral(node.Right) {
+		// Null checks: only = and != can be true
+		switch op {
+		case OpEqual:
+			c.emit(OpNullEqual)
+		case OpNotEqual:
+			c.emit(OpNullNotEqual)
+		default:
+			c.emit(OpPop)
+			c.emit(OpPop)
+			c.emit(OpFalse)
+		}
+		return nil
+	}
+
+	if !ok {
+		// String operators keep their node, which holds compiled patterns
+		c.program.nodes = append(c.program.nodes, node)
+		c.emit(OpMatch, len(c.program.nodes)-1)
+		return nil
+	}
+	c.emit(op)
+	return nil
+}
+
+// membership compiles IN and NOT IN. A missing value is only in a list
+// literal holding null, and list literals look the value up in their
+// constant elements first.
+func (c *compiler) membership(node *parser.Node) error {
+	negate := 0
+	if node.Value == "NOT IN" {
+		negate = 1
+	}
+
+	if err := c.expression(node.Left); err != nil {
+		return err
+	}
+	list := node.Right
+	literal := list != nil && list.Type == parser.ListLiteral && list.Set != nil
+
+	// A missing value is looked up like any other in a set holding null
+	missing := -1
+	if !literal || !list.Set.Contains(nil) {
+		missing = c.emitJump(OpJumpIfNilFalse)
+	}
+
+	if literal {
+		c.program.lists = append(c.program.lists, list)
+		found := c.emitJump(OpInSet, len(c.program.lists)-1, negate)
+		for _, element := range list.Set.Dynamic {
+			if err := c.expression(element); err != nil {
+				return err
+			}
+		}
+		c.emit(OpInList, len(list.Set.Dynamic), negate)
+		c.patch(found)
+	} else {
+		if err := c.expression(list); err != nil {
+			return err
+		}
+		c.emit(OpInValue, negate)
+	}
+
+	if missing >= 0 {
+		c.patch(missing)
+	}
+	return nil
+}
+
+// expression compiles a node evaluated for its value, the counterpart of
+// evaluateExpression.
+func (c *compiler) expression(node *parser.Node) error {
+	if node == nil {
+		c.emit(OpConst, c.constant(nil))
+		return nil
+	}
+
+	switch node.Type {
+	case parser.Identifier:
+		c.emit(OpLoad, c.constant(node.Value))
+	case parser.NumericLiteral, parser.StringLiteral, parser.BooleanLiteral, parser.NullLiteral:
+		// An invalid numeric literal has no value
+		value, _ := node.LiteralValue()
+		c.emit(OpConst, c.constant(value))
+	case parser.DurationLiteral:
+		duration, ok := node.LiteralValue()
+		if !ok {
+			c.emit(OpFail, c.constant(fmt.Errorf("invalid duration literal: '%s'", node.Value)))
+			return nil
+		}
+		c.emit(OpConst, c.constant(duration))
+	case parser.ListLiteral:
+		for _, element := range node.Elements {
+			if err := c.expression(element); err != nil {
+				return err
+			}
+		}
+		c.emit(OpList, len(node.Elements))
+	case parser.AdditiveExpression, parser.MultiplicativeExpression:
+		op, ok := arithmeticOperators[node.Value]
+		if !ok {
+			return fmt.Errorf("unknown arithmetic operator: %s", node.Value)
+		}
+		if err := c.expression(node.Left); err != nil {
+			return err
+		}
+		if err := c.expression(node.Right); err != nil {
+			return err
+		}
+		c.emit(op)
+	case parser.UnaryExpression:
+		if err := c.expression(node.Left); err != nil {
+			return err
+		}
+		c.emit(OpSign, c.constant(node.Value))
+	case parser.MemberExpression:
+		return c.member(node)
+	case parser.CallExpression:
+		return c.call(node)
+	default:
+		// Conditions used as values have no value
+		c.emit(OpConst, c.constant(nil))
+	}
+	return nil
+}
+
+// member compiles one step of an attribute path. A computed key is only
+// evaluated when the object is present.
+func (c *compiler) member(node *parser.Node) error {
+	if err := c.expression(node.Left); err != nil {
+		return err
+	}
+	if node.Value == "." {
+		c.emit(OpMember, c.constant(node.Right.Value))
+		return nil
+	}
+
+	missing := c.emitJump(OpJumpIfNil)
+	if err := c.expression(node.Right); err != nil {
+		return err
+	}
+	c.emit(OpIndex)
+	c.patch(missing)
+	return nil
+}
+
+// call compiles a function call. Each argument is converted as soon as it is
+// evaluated, and a missing argument skips the rest of the call.
+func (c *compiler) call(node *parser.Node) error {
+	fn, ok := interpreter.Functions.Lookup(node.Value)
+	if !ok {
+		c.emit(OpFail, c.constant(fmt.Errorf("unknown function: %s", node.Value)))
+		return nil
+	}
+	if err := fn.CheckArity(len(node.Arguments)); err != nil {
+		c.emit(OpFail, c.constant(err))
+		return nil
+	}
+	if len(node.Arguments) > math.MaxUint8 {
+		return fmt.Errorf("function %s is called with %d arguments, at most %d are supported", fn.Name, len(node.Arguments), math.MaxUint8)
+	}
+
+	c.program.calls = append(c.program.calls, call{function: fn, arguments: len(node.Arguments)})
+	site := len(c.program.calls) - 1
+
+	var missing []int
+	for i, argument := range node.Arguments {
+		if err := c.expression(argument); err != nil {
+			return err
+		}
+		missing = append(missing, c.emitJump(OpArgument, site, i))
+	}
+	c.emit(OpCall, site)
+	c.depth += 1 - len(node.Arguments)
+	for _, jump := range missing {
+		c.patch(jump)
+	}
+	return nil
+}
+
+// constant returns the index of a constant, adding it to the program the
+// first time it is used.
+func (c *compiler) constant(constant interface{}) int {
+	if index, ok := c.constants[constant]; ok {
+		return index
+	}
+
+	v := value{ref: constant}
+	if num, ok := constant.(float64); ok {
+		// Numeric literals keep their boxed value, so they are never boxed again
+		v = value{ref: constant, num: num, kind: kindNumber}
+	}
+	name, _ := constant.(string)
+	c.program.constants = append(c.program.constants, v)
+	c.program.names = append(c.program.names, name)
+	c.constants[constant] = len(c.program.constants) - 1
+	return len(c.program.constants) - 1
+}
+
+// emit appends an instruction and tracks the size of the stack.
+func (c *compiler) emit(op Opcode, operands ...int) {
+	code := append(c.program.code, byte(op))
+	for i, width := range definitions[op].operands {
+		if width == 1 {
+			code = append(code, byte(operands[i]))
+		} else {
+			code = append(code, byte(operands[i]>>8), byte(operands[i]))
+		}
+	}
+	c.program.code = code
+
+	c.depth += stackEffect(op, operands)
+	if c.depth > c.maxDepth {
+		c.maxDepth = c.depth
+	}
+}
+
+// emitJump appends an instruction whose last operand is a jump target, to be
+// set by patch, and returns the position of that operand.
+func (c *compiler) emitJump(op Opcode, operands ...int) int {
+	c.emit(op, append(operands, 0)...)
+	return len(c.program.code) - 2
+}
+
+// patch points the jump target at position to the next instruction.
+func (c *compiler) patch(position int) {
+	target := len(c.program.code)
+	c.program.code[position] = byte(target >> 8)
+	c.program.code[position+1] = byte(target)
+}
+
+// stackEffect returns how an instruction changes the size of the stack when
+// the machine does not jump. OpCall is accounted for by call.
+func stackEffect(op Opcode, operands []int) int {
+	switch op {
+	case OpConst, OpLoad, OpHas, OpTrue, OpFalse, OpFail:
+		return 1
+	case OpPop, OpJumpIfFalseOrPop, OpJumpIfTrueOrPop,
+		OpEqual, OpNotEqual, OpGreater, OpLess, OpGreaterEqual, OpLessEqual, OpMatch,
+		OpNullEqual, OpNullNotEqual, OpInValue,
+		OpAdd, OpSubtract, OpMultiply, OpDivide, OpModulo, OpIndex:
+		return -1
+	case OpInList:
+		return -operands[0]
+	case OpList:
+		return 1 - operands[0]
+	}
+	return 0
+}
+
+// isNullLiteral tests for a null literal.
+func isNullLiteral(node *parser.Node) bool {
+	return node != nil && node.Type == parser.NullLiteral
+}
@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Disassemble returns the bytecode of the program, one instruction per line:
+// its offset, name and operands, followed by what the operands refer to.
+//
+//	0000 LOAD 0 ; age
+//	0003 CONST 1 ; 30
+//	0006 GREATER
+func (p *Program) Disassemble() string {
+	var b strings.Builder
+	for ip := 0; ip < len(p.code); {
+		op := Opcode(p.code[ip])
+		fmt.Fprintf(&b, "%04d %s", ip, op)
+		if int(op) >= len(definitions) {
+			b.WriteString("\n")
+			ip++
+			continue
+		}
+
+		operands := make([]int, len(definitions[op].operands))
+		position := ip + 1
+		for i, width := range definitions[op].operands {
+			if width == 1 {
+				operands[i] = int(p.code[position])
+			} else {
+				operands[i] = operand(p.code, position)
+			}
+			fmt.Fprintf(&b, " %d", operands[i])
+			position += width
+		}
+
+		if comment := p.comment(op, operands); comment != "" {
+			b.WriteString(" ; " + comment)
+		}
+		b.WriteString("\n")
+		ip = position
+	}
+	return b.String()
+}
+
+// comment describes what the operands of an instruction refer to.
+func (p *Program) comment(op Opcode, operands []int) string {
+	switch op {
+	case OpLoad, OpHas, OpSign, OpMember:
+		return p.names[operands[0]]
+	case OpConst, OpFail:
+		return formatConstant(p.constants[operands[0]].ref)
+	case OpMatch:
+		return parser.Format(p.nodes[operands[0]])
+	case OpInSet:
+		return membership(operands[1]) + " " + parser.Format(p.lists[operands[0]])
+	case OpInList:
+		return membership(operands[1])
+	case OpInValue:
+		return membership(operands[0])
+	case OpArgument:
+		return fmt.Sprintf("argument %d of %s", operands[1]+1, p.calls[operands[0]].function.Name)
+	case OpCall:
+		return p.calls[operands[0]].function.Name
+	}
+	return ""
+}
+
+// formatConstant formats a constant like the rule language writes it.
+func formatConstant(constant interface{}) string {
+	switch c := constant.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", c)
+	case error:
+		return c.Error()
+	}
+	return fmt.Sprint(constant)
+}
+
+// membership names the operator of a membership test.
+func membership(negate int) string {
+	if negate == 1 {
+		return "NOT IN"
+	}
+	return "IN"
+}
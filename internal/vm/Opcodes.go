@@ -0,0 +1,172 @@
+// Package vm compiles rule ASTs into bytecode and runs it on a stack
+// machine. A Program gives the same results as interpreter.Interpret, and
+// evaluating one allocates nothing, whatever the types of the attributes,
+// unless the rule calls functions, works with dates or durations, builds
+// lists or fails with an error. Unlike the interpreter, it does not print
+// comparisons of mismatched types.
+package vm
+
+// Opcode is the first byte of an instruction. Its operands follow it, each
+// one or two bytes wide, big endian.
+type Opcode byte
+
+// The instructions of the machine. Stack effects are written as
+// [before] -> [after], with the top of the stack on the right.
+const (
+	// OpConst pushes a constant: [] -> [value]
+	OpConst Opcode = iota
+	// OpLoad pushes the attribute named by a constant: [] -> [value]
+	OpLoad
+	// OpHas pushes whether the attribute named by a constant is present:
+	// [] -> [bool]
+	OpHas
+	// OpTrue pushes true: [] -> [true]
+	OpTrue
+	// OpFalse pushes false: [] -> [false]
+	OpFalse
+	// OpPop drops the top of the stack: [value] -> []
+	OpPop
+	// OpTest turns a value used as a condition into a bool: a bool is used
+	// as is, anything else is true if present: [value] -> [bool]
+	OpTest
+	// OpPresent pushes whether a value is present: [value] -> [bool]
+	OpPresent
+	// OpNot negates a bool: [bool] -> [bool]
+	OpNot
+	// OpJumpIfFalseOrPop jumps to its target if the top is false, and drops
+	// it otherwise
+	OpJumpIfFalseOrPop
+	// OpJumpIfTrueOrPop jumps to its target if the top is true, and drops it
+	// otherwise
+	OpJumpIfTrueOrPop
+	// OpJumpIfNil jumps to its target, keeping the value, if the top is
+	// missing
+	OpJumpIfNil
+	// OpJumpIfNilFalse replaces a missing top by false and jumps to its
+	// target
+	OpJumpIfNilFalse
+	// OpEqual and the other comparisons compare two values:
+	// [left right] -> [bool]
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpLess
+	OpGreaterEqual
+	OpLessEqual
+	// OpMatch evaluates the string operator of the node given by its
+	// operand: [left right] -> [bool]
+	OpMatch
+	// OpNullEqual tests whether both values are missing:
+	// [left right] -> [bool]
+	OpNullEqual
+	// OpNullNotEqual tests whether either value is present:
+	// [left right] -> [bool]
+	OpNullNotEqual
+	// OpInSet looks the value up in the constant elements of a list. If it
+	// is found, the value is replaced by the result of IN, or NOT IN if the
+	// second operand is set, and the machine jumps to the third operand.
+	OpInSet
+	// OpInList compares the value with the number of candidates given by
+	// its operand and pushes the result of IN, or NOT IN if the second
+	// operand is set: [value candidates...] -> [bool]
+	OpInList
+	// OpInValue tests whether the value is an element of a list, or not if
+	// the operand is set: [value list] -> [bool]
+	OpInValue
+	// OpAdd and the other arithmetic operators compute a value:
+	// [left right] -> [value]
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulo
+	// OpSign applies the sign named by a constant: [value] -> [value]
+	OpSign
+	// OpMember looks up the key given by a constant: [object] -> [value]
+	OpMember
+	// OpIndex looks up a computed key: [object key] -> [value]
+	OpIndex
+	// OpArgument converts the argument of the call given by the first
+	// operand at the position given by the second. A missing argument makes
+	// the call missing: the arguments are dropped, nil is pushed and the
+	// machine jumps to the third operand.
+	OpArgument
+	// OpCall calls a function: [arguments...] -> [value]
+	OpCall
+	// OpList builds a list of the number of values given by its operand:
+	// [values...] -> [list]
+	OpList
+	// OpFail stops the machine with the error held by a constant
+	OpFail
+)
+
+// definition describes an opcode: its name and the width of its operands.
+type definition struct {
+	name     string
+	operands []int
+}
+
+// definitions holds the definition of every opcode.
+var definitions = [...]definition{
+	OpConst:            {"CONST", []int{2}},
+	OpLoad:             {"LOAD", []int{2}},
+	OpHas:              {"HAS", []int{2}},
+	OpTrue:             {"TRUE", nil},
+	OpFalse:            {"FALSE", nil},
+	OpPop:              {"POP", nil},
+	OpTest:             {"TEST", nil},
+	OpPresent:          {"PRESENT", nil},
+	OpNot:              {"NOT", nil},
+	OpJumpIfFalseOrPop: {"JUMP_IF_FALSE_OR_POP", []int{2}},
+	OpJumpIfTrueOrPop:  {"JUMP_IF_TRUE_OR_POP", []int{2}},
+	OpJumpIfNil:        {"JUMP_IF_NIL", []int{2}},
+	OpJumpIfNilFalse:   {"JUMP_IF_NIL_FALSE", []int{2}},
+	OpEqual:            {"EQUAL", nil},
+	OpNotEqual:         {"NOT_EQUAL", nil},
+	OpGreater:          {"GREATER", nil},
+	OpLess:             {"LESS", nil},
+	OpGreaterEqual:     {"GREATER_EQUAL", nil},
+	OpLessEqual:        {"LESS_EQUAL", nil},
+	OpMatch:            {"MATCH", []int{2}},
+	OpNullEqual:        {"NULL_EQUAL", nil},
+	OpNullNotEqual:     {"NULL_NOT_EQUAL", nil},
+	OpInSet:            {"IN_SET", []int{2, 1, 2}},
+	OpInList:           {"IN_LIST", []int{2, 1}},
+	OpInValue:          {"IN_VALUE", []int{1}},
+	OpAdd:              {"ADD", nil},
+	OpSubtract:         {"SUBTRACT", nil},
+	OpMultiply:         {"MULTIPLY", nil},
+	OpDivide:           {"DIVIDE", nil},
+	OpModulo:           {"MODULO", nil},
+	OpSign:             {"SIGN", []int{2}},
+	OpMember:           {"MEMBER", []int{2}},
+	OpIndex:            {"INDEX", nil},
+	OpArgument:         {"ARGUMENT", []int{2, 1, 2}},
+	OpCall:             {"CALL", []int{2}},
+	OpList:             {"LIST", []int{2}},
+	OpFail:             {"FAIL", []int{2}},
+}
+
+// operators maps the comparison and arithmetic opcodes to the operators of
+// the rule language, which temporal values are compared and computed with.
+var operators = [...]string{
+	OpEqual:        "=",
+	OpNotEqual:     "!=",
+	OpGreater:      ">",
+	OpLess:         "<",
+	OpGreaterEqual: ">=",
+	OpLessEqual:    "<=",
+	OpAdd:          "+",
+	OpSubtract:     "-",
+	OpMultiply:     "*",
+	OpDivide:       "/",
+	OpModulo:       "%",
+}
+
+// String returns the name of the opcode.
+func (op Opcode) String() string {
+	if int(op) < len(definitions) {
+		return definitions[op].name
+	}
+	return "UNKNOWN"
+}
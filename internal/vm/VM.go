@@ -0,0 +1,573 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// kind tells which field of a value holds it.
+type kind uint8
+
+const (
+	// kindValue values are held by ref, which is nil when missing
+	kindValue kind = iota
+	// kindNumber values are numbers held by num, so arithmetic never boxes
+	// them. ref holds the boxed number of numeric literals.
+	kindNumber
+	// kindBool values are the results of conditions, held by truth
+	kindBool
+)
+
+// value is an element of the stack.
+type value struct {
+	ref   interface{}
+	num   float64
+	kind  kind
+	truth bool
+}
+
+// smallStack is the size of the stack of programs evaluated on the stack of
+// the calling goroutine. Deeper programs use pooled stacks.
+const smallStack = 16
+
+// Run evaluates the program against the given context data and returns any
+// evaluation error, like interpreter.Evaluate.
+func (p *Program) Run(context interpreter.Context) (bool, error) {
+	if p.stackSize <= smallStack {
+		var stack [smallStack]value
+		return run(p, stack[:], context)
+	}
+
+	stack := p.stacks.Get().(*[]value)
+	result, err := run(p, *stack, context)
+	// Drop the references to the context before the stack is reused
+	clear(*stack)
+	p.stacks.Put(stack)
+	return result, err
+}
+
+// run executes the bytecode of a program.
+func run(p *Program, stack []value, context interpreter.Context) (bool, error) {
+	code, sp := p.code, 0
+
+	for ip := 0; ip < len(code); {
+		op := Opcode(code[ip])
+		ip++
+
+		switch op {
+		case OpConst:
+			stack[sp] = p.constants[operand(code, ip)]
+			sp++
+			ip += 2
+		case OpLoad:
+			stack[sp] = value{ref: context[p.names[operand(code, ip)]]}
+			sp++
+			ip += 2
+		case OpHas:
+			stack[sp] = boolean(context[p.names[operand(code, ip)]] != nil)
+			sp++
+			ip += 2
+		case OpTrue, OpFalse:
+			stack[sp] = boolean(op == OpTrue)
+			sp++
+		case OpPop:
+			sp--
+		case OpTest:
+			if b, ok := stack[sp-1].ref.(bool); ok {
+				stack[sp-1] = boolean(b)
+			} else {
+				stack[sp-1] = boolean(!stack[sp-1].missing())
+			}
+		case OpPresent:
+			stack[sp-1] = boolean(!stack[sp-1].missing())
+		case OpNot:
+			stack[sp-1].truth = !stack[sp-1].truth
+		case OpJumpIfFalseOrPop, OpJumpIfTrueOrPop:
+			if stack[sp-1].truth == (op == OpJumpIfTrueOrPop) {
+				ip = operand(code, ip)
+			} else {
+				sp--
+				ip += 2
+			}
+		case OpJumpIfNil:
+			if stack[sp-1].missing() {
+				ip = operand(code, ip)
+			} else {
+				ip += 2
+			}
+		case OpJumpIfNilFalse:
+			if stack[sp-1].missing() {
+				stack[sp-1] = boolean(false)
+				ip = operand(code, ip)
+			} else {
+				ip += 2
+			}
+		case OpEqual, OpNotEqual, OpGreater, OpLess, OpGreaterEqual, OpLessEqual:
+			sp--
+			stack[sp-1] = boolean(compare(op, &stack[sp-1], &stack[sp]))
+		case OpMatch:
+			sp--
+			result, err := match(p.nodes[operand(code, ip)], &stack[sp-1], &stack[sp])
+			if err != nil {
+				return false, err
+			}
+			stack[sp-1] = boolean(result)
+			ip += 2
+		case OpNullEqual:
+			sp--
+			stack[sp-1] = boolean(stack[sp-1].missing() && stack[sp].missing())
+		case OpNullNotEqual:
+			sp--
+			stack[sp-1] = boolean(!stack[sp-1].missing() || !stack[sp].missing())
+		case OpInSet:
+			negate := code[ip+2] == 1
+			if stack[sp-1].in(p.lists[operand(code, ip)].Set) {
+				stack[sp-1] = boolean(!negate)
+				ip = operand(code, ip+3)
+			} else {
+				ip += 5
+			}
+		case OpInList:
+			count, negate := operand(code, ip), code[ip+2] == 1
+			sp -= count
+			found := false
+			for i := sp; i < sp+count; i++ {
+				found = found || matches(&stack[sp-1], &stack[i])
+			}
+			stack[sp-1] = boolean(found != negate)
+			ip += 3
+		case OpInValue:
+			sp--
+			found, present, err := contains(&stack[sp-1], &stack[sp])
+			if err != nil {
+				return false, err
+			}
+			stack[sp-1] = boolean(present && found != (code[ip] == 1))
+			ip++
+		case OpAdd, OpSubtract, OpMultiply, OpDivide, OpModulo:
+			sp--
+			result, err := arithmetic(op, &stack[sp-1], &stack[sp])
+			if err != nil {
+				return false, err
+			}
+			stack[sp-1] = result
+		case OpSign:
+			result, err := sign(p.names[operand(code, ip)], &stack[sp-1])
+			if err != nil {
+				return false, err
+			}
+			stack[sp-1] = result
+			ip += 2
+		case OpMember:
+			if object := &stack[sp-1]; !object.missing() {
+				result, err := interpreter.LookupMember(object.box(), p.constants[operand(code, ip)].ref)
+				if err != nil {
+					return false, err
+				}
+				stack[sp-1] = value{ref: result}
+			}
+			ip += 2
+		case OpIndex:
+			sp--
+			if key := &stack[sp]; key.missing() {
+				stack[sp-1] = value{}
+			} else {
+				result, err := interpreter.LookupMember(stack[sp-1].box(), key.box())
+				if err != nil {
+					return false, err
+				}
+				stack[sp-1] = value{ref: result}
+			}
+		case OpArgument:
+			fn, i := p.calls[operand(code, ip)].function, int(code[ip+2])
+			argument := &stack[sp-1]
+			if argument.missing() {
+				// A missing argument makes the result missing
+				sp -= i
+				stack[sp-1] = value{}
+				ip = operand(code, ip+3)
+				continue
+			}
+			converted, err := fn.ConvertArgument(i, argument.box())
+			if err != nil {
+				return false, fmt.Errorf("argument %d of %s: %w", i+1, fn.Name, err)
+			}
+			stack[sp-1] = value{ref: converted}
+			ip += 5
+		case OpCall:
+			call := p.calls[operand(code, ip)]
+			args := make([]interface{}, call.arguments)
+			sp -= call.arguments
+			for i := range args {
+				args[i] = stack[sp+i].ref
+			}
+			result, err := call.function.Call(args)
+			if err != nil {
+				return false, err
+			}
+			stack[sp] = value{ref: result}
+			sp++
+			ip += 2
+		case OpList:
+			count := operand(code, ip)
+			list := make([]interface{}, count)
+			sp -= count
+			for i := range list {
+				list[i] = stack[sp+i].box()
+			}
+			stack[sp] = value{ref: list}
+			sp++
+			ip += 2
+		case OpFail:
+			return false, p.constants[operand(code, ip)].ref.(error)
+		default:
+			return false, fmt.Errorf("unknown opcode %d at %d", op, ip-1)
+		}
+	}
+
+	return stack[0].truth, nil
+}
+
+// operand reads the two byte operand at ip.
+func operand(code []byte, ip int) int {
+	return int(code[ip])<<8 | int(code[ip+1])
+}
+
+// boolean returns the value of a condition.
+func boolean(truth bool) value {
+	return value{kind: kindBool, truth: truth}
+}
+
+// missing reports whether a value is nil: a missing attribute or JSON null.
+func (v *value) missing() bool {
+	return v.kind == kindValue && v.ref == nil
+}
+
+// box returns the value as an interface{}, allocating only for numbers
+// computed by the machine.
+func (v *value) box() interface{} {
+	if v.kind == kindNumber && v.ref == nil {
+		return v.num
+	}
+	return v.ref
+}
+
+// number converts a value to a number like interpreter.ToNumber, without
+// boxing it or allocating an error for strings that are not numbers.
+func (v *value) number() (float64, bool) {
+	if num, ok := v.exact(); ok {
+		return num, true
+	}
+	if str, ok := v.ref.(string); ok && maybeNumber(str) {
+		num, err := strconv.ParseFloat(str, 64)
+		return num, err == nil
+	}
+	return 0, false
+}
+
+// exact returns the number held by a value when = compares it as a number:
+// any Go number or a json.Number, but not a numeric string. It converts them
+// like interpreter.NormalizeNumber, without boxing the result.
+func (v *value) exact() (float64, bool) {
+	if v.kind == kindNumber {
+		return v.num, true
+	}
+	switch n := v.ref.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case json.Number:
+		if maybeNumber(string(n)) {
+			num, err := n.Float64()
+			return num, err == nil
+		}
+	}
+	return 0, false
+}
+
+// maybeNumber reports whether strconv.ParseFloat may accept a string, so
+// that strings that are not numbers are rejected without the error it
+// allocates. It checks the shape of decimal numbers and leaves the details,
+// such as where underscores may go, hexadecimal numbers, infinities and NaN,
+// to ParseFloat.
+func maybeNumber(str string) bool {
+	if str != "" && (str[0] == '+' || str[0] == '-') {
+		str = str[1:]
+	}
+	if len(str) > 1 && str[0] == '0' && (str[1] == 'x' || str[1] == 'X') {
+		return true
+	}
+	if strings.EqualFold(str, "inf") || strings.EqualFold(str, "infinity") || strings.EqualFold(str, "nan") {
+		return true
+	}
+
+	i, digits := 0, 0
+	for ; i < len(str) && isDigit(str[i]); i++ {
+		if str[i] != '_' {
+			digits++
+		}
+	}
+	if i < len(str) && str[i] == '.' {
+		for i++; i < len(str) && isDigit(str[i]); i++ {
+			if str[i] != '_' {
+				digits++
+			}
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(str) && (str[i] == 'e' || str[i] == 'E') {
+		i++
+		if i < len(str) && (str[i] == '+' || str[i] == '-') {
+			i++
+		}
+		exponent := i
+		for ; i < len(str) && isDigit(str[i]); i++ {
+		}
+		if i == exponent {
+			return false
+		}
+	}
+	return i == len(str)
+}
+
+// isDigit reports whether a byte is a decimal digit or an underscore, which
+// may separate digits.
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9' || b == '_'
+}
+
+// temporal reports whether a value is a timestamp or a duration.
+func (v *value) temporal() bool {
+	return v.kind == kindValue && interpreter.IsTemporal(v.ref)
+}
+
+// in looks the value up in the constant elements of a list, which holds
+// numbers as float64.
+func (v *value) in(set *parser.ValueSet) bool {
+	if num, ok := v.exact(); ok {
+		return set.Contains(num)
+	}
+	return set.Contains(v.ref)
+}
+
+// equal compares two values like interpreter.Equal. Values of mismatched
+// types are not equal; unlike interpreter.Equal, no error describing them is
+// built, so comparing them does not allocate.
+func equal(left, right *value) bool {
+	if leftNum, ok := left.exact(); ok {
+		rightNum, ok := right.exact()
+		return ok && leftNum == rightNum
+	}
+
+	switch l := left.ref.(type) {
+	case string:
+		r, ok := right.ref.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.ref.(bool)
+		return ok && l == r
+	}
+	return false
+}
+
+// sameType reports whether = can compare two values: two numbers, two
+// strings or two bools.
+func sameType(left, right *value) bool {
+	if _, ok := left.exact(); ok {
+		_, ok := right.exact()
+		return ok
+	}
+
+	switch left.ref.(type) {
+	case string:
+		_, ok := right.ref.(string)
+		return ok
+	case bool:
+		_, ok := right.ref.(bool)
+		return ok
+	}
+	return false
+}
+
+// compare evaluates a comparison of two values, the counterpart of the end
+// of evaluateBinaryExpression.
+func compare(op Opcode, left, right *value) bool {
+	if left.missing() || right.missing() {
+		return false
+	}
+	if left.temporal() || right.temporal() {
+		return interpreter.CompareTemporal(operators[op], left.box(), right.box())
+	}
+
+	switch op {
+	case OpEqual:
+		return equal(left, right)
+	case OpNotEqual:
+		// Values of mismatched types are neither equal nor different
+		return sameType(left, right) && !equal(left, right)
+	}
+
+	leftNum, leftOk := left.number()
+	rightNum, rightOk := right.number()
+	if !leftOk || !rightOk {
+		return false
+	}
+	switch op {
+	case OpGreater:
+		return leftNum > rightNum
+	case OpLess:
+		return leftNum < rightNum
+	case OpGreaterEqual:
+		return leftNum >= rightNum
+	}
+	return leftNum <= rightNum
+}
+
+// match evaluates a string operator on two values.
+func match(node *parser.Node, left, right *value) (bool, error) {
+	if left.missing() || right.missing() {
+		return false, nil
+	}
+	if left.temporal() || right.temporal() {
+		return interpreter.CompareTemporal(node.Value, left.box(), right.box()), nil
+	}
+	if list, ok := left.ref.([]interface{}); ok && node.Value == "CONTAINS" {
+		// List membership, compared here so mismatched elements do not allocate
+		for _, element := range list {
+			if equal(&value{ref: element}, right) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return interpreter.MatchString(node, left.box(), right.box())
+}
+
+// matches tests whether a list element equals a value.
+func matches(v, candidate *value) bool {
+	if candidate.missing() {
+		return false
+	}
+	return equal(v, candidate)
+}
+
+// contains tests whether a value is an element of a list. A missing list is
+// not present.
+func contains(v, list *value) (found, present bool, err error) {
+	if list.missing() {
+		return false, false, nil
+	}
+
+	listValue := list.box()
+	candidates, ok := interpreter.ToList(listValue)
+	if !ok {
+		return false, false, fmt.Errorf("right side of IN must be a list, got '%v' of type %T", listValue, listValue)
+	}
+	for _, candidate := range candidates {
+		if matches(v, &value{ref: candidate}) {
+			return true, true, nil
+		}
+	}
+	return false, true, nil
+}
+
+// arithmetic applies an arithmetic operator, the counterpart of
+// evaluateArithmeticExpression.
+func arithmetic(op Opcode, left, right *value) (value, error) {
+	if left.missing() || right.missing() {
+		return value{}, nil
+	}
+	operator := operators[op]
+	if left.temporal() || right.temporal() {
+		result, err := interpreter.TemporalArithmetic(operator, left.box(), right.box())
+		return value{ref: result}, err
+	}
+
+	leftNum, ok := left.number()
+	if !ok {
+		return value{}, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, left.box(), left.box())
+	}
+	rightNum, ok := right.number()
+	if !ok {
+		return value{}, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, right.box(), right.box())
+	}
+
+	var result float64
+	switch op {
+	case OpAdd:
+		result = leftNum + rightNum
+	case OpSubtract:
+		result = leftNum - rightNum
+	case OpMultiply:
+		result = leftNum * rightNum
+	case OpDivide:
+		if rightNum == 0 {
+			return value{}, fmt.Errorf("division by zero: %v / %v", left.box(), right.box())
+		}
+		result = leftNum / rightNum
+	case OpModulo:
+		if rightNum == 0 {
+			return value{}, fmt.Errorf("modulo by zero: %v %% %v", left.box(), right.box())
+		}
+		result = math.Mod(leftNum, rightNum)
+	}
+	return value{num: result, kind: kindNumber}, nil
+}
+
+// sign applies a leading '+' or '-', the counterpart of
+// evaluateSignExpression.
+func sign(operator string, v *value) (value, error) {
+	if v.missing() {
+		return *v, nil
+	}
+
+	if duration, ok := v.ref.(time.Duration); ok {
+		if operator == "-" {
+			return value{ref: -duration}, nil
+		}
+		return *v, nil
+	}
+
+	num, ok := v.number()
+	if !ok {
+		return value{}, fmt.Errorf("cannot apply '%s' to non-numeric value '%v' of type %T", operator, v.box(), v.box())
+	}
+
+	switch operator {
+	case "-":
+		return value{num: -num, kind: kindNumber}, nil
+	case "+":
+		return value{num: num, kind: kindNumber}, nil
+	}
+	return value{}, fmt.Errorf("unknown unary operator in expression: %s", operator)
+}
@@ -0,0 +1,134 @@
+package Test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+func TestRuleCache(t *testing.T) {
+	rules := map[int]string{1: "age > 30", 2: "dept IN ['Sales', 'HR']"}
+	loads := map[int]int{}
+	var mu sync.Mutex
+
+	names := map[string]int{"sales-or-hr": 2}
+	resolves := 0
+
+	cache := interpreter.NewRuleCache(func(id, version int) (*interpreter.StoredRule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads[id]++
+		rule, ok := rules[id]
+		if !ok {
+			return nil, errors.New("rule not found")
+		}
+		ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+		if err != nil {
+			return nil, err
+		}
+		stored := &interpreter.StoredRule{ID: id, Version: 1, Enabled: true, AST: ast}
+		for name, named := range names {
+			if named == id {
+				stored.Name = name
+			}
+		}
+		return stored, nil
+	}, func(name string) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resolves++
+		id, ok := names[name]
+		if !ok {
+			return 0, errors.New("rule not found")
+		}
+		return id, nil
+	})
+
+	// Concurrent lookups share the cached AST
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rule, err := cache.Get(2)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if !interpreter.Interpret(rule.AST, interpreter.Context{"dept": "HR"}) {
+				t.Errorf("Expected cached rule to match")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := cache.Get(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads[2] > 50 || cache.Len() != 1 {
+		t.Errorf("Expected rule 2 to be cached, loaded %d times, %d cached", loads[2], cache.Len())
+	}
+	loadsBefore := loads[2]
+	cache.Get(2)
+	if loads[2] != loadsBefore {
+		t.Errorf("Expected a cached rule not to be reloaded")
+	}
+
+	// Named rules are resolved once and then found in the cache
+	for i := 0; i < 3; i++ {
+		rule, err := cache.GetByName("sales-or-hr")
+		if err != nil || rule.ID != 2 {
+			t.Fatalf("Expected rule 2 by name, got %+v, %v", rule, err)
+		}
+	}
+	if resolves != 0 {
+		t.Errorf("Expected the name of a cached rule not to be resolved, resolved %d times", resolves)
+	}
+	if _, err := cache.GetByName("unknown"); err == nil {
+		t.Errorf("Expected an error for an unknown name")
+	}
+
+	// Renaming a rule and invalidating it forgets the old name
+	mu.Lock()
+	delete(names, "sales-or-hr")
+	names["sales-and-hr"] = 2
+	mu.Unlock()
+	cache.Invalidate(2)
+	if _, err := cache.GetByName("sales-or-hr"); err == nil {
+		t.Errorf("Expected the old name to be forgotten")
+	}
+	if rule, err := cache.GetByName("sales-and-hr"); err != nil || rule.Name != "sales-and-hr" {
+		t.Errorf("Expected rule 2 by its new name, got %+v, %v", rule, err)
+	}
+
+	// Changing a rule and invalidating it reloads the new definition
+	rule, _ := cache.Get(1)
+	if !interpreter.Interpret(rule.AST, interpreter.Context{"age": 35}) {
+		t.Errorf("Expected age 35 to match the original rule")
+	}
+	mu.Lock()
+	rules[1] = "age > 40"
+	mu.Unlock()
+	cache.Invalidate(1)
+	rule, _ = cache.Get(1)
+	if interpreter.Interpret(rule.AST, interpreter.Context{"age": 35}) {
+		t.Errorf("Expected age 35 not to match the updated rule")
+	}
+
+	// Load errors are returned and not cached
+	if _, err := cache.Get(3); err == nil {
+		t.Errorf("Expected an error for an unknown rule")
+	}
+	cache.Get(3)
+	if loads[3] != 2 {
+		t.Errorf("Expected failed loads not to be cached, loaded %d times", loads[3])
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 {
+		t.Errorf("Expected an empty cache after Clear, got %d rules", cache.Len())
+	}
+}
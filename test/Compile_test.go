@@ -0,0 +1,218 @@
+package Test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Rules covering every kind of node, every operator and the error paths,
+// evaluated against every one of differentialContexts by the tests comparing
+// other evaluators with the interpreter
+var differentialRules = []string{
+	"age > 30",
+	"age >= 30 AND age <= 40",
+	"age < 18 OR age > 65",
+	"salary = 60000 OR department = 'Sales'",
+	"department != 'Sales' AND department <> 'HR'",
+	"NOT age > 30",
+	"NOT (active OR verified)",
+	"!active && verified || age > 50",
+	"active",
+	"active = true",
+	"manager = null",
+	"manager != null",
+	"null = manager",
+	"age > null",
+	"null",
+	"true",
+	"false OR true",
+	"'age'",
+	"30",
+	"-age",
+	"-age < -30",
+	"+salary > 0",
+	"age + 5 > 35",
+	"salary / 12 > 4000",
+	"salary % 7 = 3",
+	"age * 2 - 10 >= 50",
+	"age / divisor > 1",
+	"age % divisor = 0",
+	"(age + bonus) * 2 > 100",
+	"country IN ['US', 'CA', 'MX']",
+	"country NOT IN ['US', 'CA']",
+	"age IN [30, 31, 32]",
+	"age IN [min_age, 40]",
+	"age IN [min_age / divisor, 40]",
+	"country IN countries",
+	"age NOT IN ages",
+	"manager IN [null, 'Bob']",
+	"manager NOT IN [null, 'Bob']",
+	"'US' IN country",
+	"name CONTAINS 'li'",
+	"tags CONTAINS 'vip'",
+	"name STARTS_WITH 'Al'",
+	"name ENDS_WITH 'ce'",
+	"name LIKE 'A%e'",
+	"code MATCHES '^[A-Z][0-9]{3}$'",
+	"user.address.country = 'US'",
+	"user.age > 30",
+	"orders[0].total > 100",
+	"orders[index].total > 100",
+	"orders[1.5].total > 100",
+	"user['address'].city = 'Paris'",
+	"user[0] = 1",
+	"lower(name) = 'alice'",
+	"len(tags) > 1",
+	"max(age, salary / 1000) > 50",
+	"round(score) = 4",
+	"substr(name, 0, 2) = 'Al'",
+	"abs(balance) > 100",
+	"sum(scores) > 10",
+	"concat(name, '!') ENDS_WITH '!'",
+	"signup_date > date('2024-01-01')",
+	"now() - last_login > 30d",
+	"session > 1h",
+	"session IN [1h, 2h]",
+	"timeout + 30m < 2h",
+	"-timeout < 0s",
+	"signup_date + 7d < date('2024-06-01')",
+	"created > '2024-01-01T00:00:00Z'",
+	"(age > 30 AND department = 'Sales') OR (salary > 50000 AND department = 'Marketing')",
+	"age > 30 AND salary / divisor > 100",
+	"age < 0 AND salary / divisor > 100",
+	"age > 0 OR salary / divisor > 100",
+}
+
+// Contexts with matching, mismatching, missing and ill-typed attributes
+var differentialContexts = []Context{
+	{},
+	{
+		"age": 32, "salary": 60000, "department": "Sales", "active": true, "verified": false,
+		"country": "US", "name": "Alice", "code": "A123", "tags": []interface{}{"vip", "beta"},
+		"user":   map[string]interface{}{"age": 45, "address": map[string]interface{}{"country": "US", "city": "Paris"}},
+		"orders": []interface{}{map[string]interface{}{"total": 150.5}, map[string]interface{}{"total": 20}},
+		"index":  1, "divisor": 4, "bonus": 20, "min_age": 30, "score": 3.6, "balance": -250,
+		"scores": []interface{}{4, 5, 6}, "countries": []interface{}{"US", "FR"}, "ages": []interface{}{18, 21},
+		"signup_date": "2024-03-01", "last_login": time.Now().Add(-40 * 24 * time.Hour),
+		"session": 2 * time.Hour, "timeout": "45m", "created": "2024-02-01T00:00:00Z", "manager": "Bob",
+	},
+	{
+		"age": 17.5, "salary": "70000", "department": "HR", "active": false, "verified": true,
+		"country": "FR", "name": "Bob", "code": "a1234", "tags": []interface{}{},
+		"user":   map[string]interface{}{"age": "old"},
+		"orders": []interface{}{}, "index": "first", "divisor": 0, "min_age": 17.5,
+		"score": "NaN", "balance": "lots", "scores": []interface{}{"a"}, "countries": "US",
+		"signup_date": "not a date", "last_login": "2024-01-01T00:00:00Z", "session": "30m",
+		"timeout": 90, "created": 42, "manager": nil,
+	},
+	{
+		"age": "40", "salary": 49999.5, "department": 7, "active": "yes", "country": nil,
+		"name": 12, "code": nil, "tags": "vip,beta", "user": []interface{}{1, 2},
+		"divisor": "2", "orders": map[string]interface{}{"0": map[string]interface{}{"total": 500}},
+		"bonus": nil, "ages": nil, "scores": nil, "countries": []interface{}{nil, "MX"},
+		"signup_date": time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "session": time.Duration(0),
+		"timeout": -time.Hour, "created": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+	{
+		"age": 66, "salary": 60000, "department": "Marketing", "active": true, "verified": true,
+		"country": "MX", "name": "Al", "code": "Z999", "user": Context{"age": 31},
+		"orders": []map[string]interface{}{{"total": 101}}, "index": 0.0, "divisor": 3,
+		"min_age": "66", "ages": []interface{}{66}, "score": 4.4, "balance": 100,
+	},
+}
+
+// Test that compiled rules give the same results and errors as the interpreter
+func TestCompiledRule(t *testing.T) {
+	restore := interpreter.SetClock(func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) })
+	defer restore()
+
+	for _, rule := range differentialRules {
+		ast := mustParse(t, rule)
+		compiled, err := interpreter.Compile(ast)
+		if err != nil {
+			t.Fatalf("Error compiling rule %q: %v", rule, err)
+		}
+
+		for _, context := range differentialContexts {
+			want, wantErr := interpreter.Evaluate(ast, context)
+			got, gotErr := compiled.Eval(context)
+			if got != want || fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("Rule: %s\nContext: %v\nExpected: %v, %v\nGot: %v, %v", rule, context, want, wantErr, got, gotErr)
+			}
+		}
+	}
+
+	compiled, err := interpreter.Compile(nil)
+	if err != nil {
+		t.Fatalf("Error compiling a missing rule: %v", err)
+	}
+	if result, err := compiled.Eval(Context{}); result || err != nil {
+		t.Errorf("Expected a missing rule to be false, got %v, %v", result, err)
+	}
+}
+
+// Test that node types, operators and literals the parser never produces are
+// reported when the rule is compiled
+func TestCompileErrors(t *testing.T) {
+	identifier := &parser.Node{Type: parser.Identifier, Value: "age"}
+	tests := []struct {
+		node     *parser.Node
+		expected string
+	}{
+		{&parser.Node{Type: parser.NodeKind(99)}, "unknown node type"},
+		{&parser.Node{Type: parser.BinaryExpression, Value: "~", Left: identifier, Right: identifier}, "unknown comparison operator: ~"},
+		{&parser.Node{Type: parser.BinaryExpression, Value: ">", Left: identifier,
+			Right: &parser.Node{Type: parser.NumericLiteral, Value: "1e400x"}}, "invalid numeric literal: '1e400x'"},
+		{&parser.Node{Type: parser.BinaryExpression, Value: ">", Right: identifier,
+			Left: &parser.Node{Type: parser.AdditiveExpression, Value: "^", Left: identifier, Right: identifier}}, "unknown arithmetic operator: ^"},
+	}
+
+	for _, test := range tests {
+		if _, err := interpreter.Compile(test.node); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q, got %v", test.expected, err)
+		}
+	}
+}
+
+// The rule and contexts the evaluators are benchmarked with
+const benchmarkRule = "(age > 30 AND department = 'Sales' AND salary * 12 > 500000) OR " +
+	"(country IN ['US', 'CA', 'MX'] AND user.tier = 'gold' AND name STARTS_WITH 'A')"
+
+var benchmarkContexts = []Context{
+	{"age": 32, "department": "Sales", "salary": 50000, "country": "FR", "name": "Alice"},
+	{"age": 25, "department": "HR", "salary": 30000, "country": "CA", "name": "Anna",
+		"user": map[string]interface{}{"tier": "gold"}},
+}
+
+func BenchmarkInterpret(b *testing.B) {
+	ast, err := parser.NewParser(parser.NewTokenizer(benchmarkRule)).ParseRule()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		interpreter.Interpret(ast, benchmarkContexts[i%len(benchmarkContexts)])
+	}
+}
+
+func BenchmarkCompiledRule(b *testing.B) {
+	ast, err := parser.NewParser(parser.NewTokenizer(benchmarkRule)).ParseRule()
+	if err != nil {
+		b.Fatal(err)
+	}
+	compiled, err := interpreter.Compile(ast)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		compiled.Eval(benchmarkContexts[i%len(benchmarkContexts)])
+	}
+}
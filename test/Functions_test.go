@@ -0,0 +1,114 @@
+package Test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+func TestStandardFunctions(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"lower(name) = 'bob'", Context{"name": "BoB"}, true},
+		{"upper(trim(code)) = 'AB1'", Context{"code": "  ab1 "}, true},
+		{"len(tags) > 2", Context{"tags": []interface{}{"a", "b", "c"}}, true},
+		{"len(tags) > 2", Context{"tags": []interface{}{"a"}}, false},
+		{"len(name) = 3", Context{"name": "Bob"}, true},
+		{"abs(delta) < 5", Context{"delta": -3}, true},
+		{"abs(delta) < 5", Context{"delta": -7.5}, false},
+		{"round(score) = 5", Context{"score": 4.6}, true},
+		{"max(a, b, c) = 9", Context{"a": 3, "b": 9, "c": 1}, true},
+		{"min(a, 10) = 3", Context{"a": 3}, true},
+		{"sum(orders) >= 100", Context{"orders": []interface{}{50.0, 25, 30}}, true},
+		{"avg(scores) = 2", Context{"scores": []int{1, 2, 3}}, true},
+		{"first(split(email, '@')) = 'bob'", Context{"email": "bob@corp.com"}, true},
+		{"substr(sku, 0, 2) = 'AB'", Context{"sku": "AB1234"}, true},
+		{"substr(sku, 2, 1e19) = '1234'", Context{"sku": "AB1234"}, true},
+		{"substr(sku, 1e19, 2) = ''", Context{"sku": "AB1234"}, true},
+		{"concat(first, ' ', last) = 'Ada Lovelace'", Context{"first": "Ada", "last": "Lovelace"}, true},
+		{"lower(user.name) IN ['alice', 'bob']", Context{"user": map[string]interface{}{"name": "Alice"}}, true},
+		{"lower(name) = 'bob'", Context{}, false}, // Missing arguments make the call result missing
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+// Test that substr rejects negative and fractional starts and lengths
+func TestSubstrErrors(t *testing.T) {
+	tests := []struct {
+		rule    string
+		message string
+	}{
+		{"substr(sku, -1, 2) = 'AB'", "substr start must not be negative, got -1"},
+		{"substr(sku, 0, -2) = 'AB'", "substr length must not be negative, got -2"},
+		{"substr(sku, 0.5, 2) = 'AB'", "substr start must be a whole number, got 0.5"},
+		{"substr(sku, 0, 1.5) = 'AB'", "substr length must be a whole number, got 1.5"},
+	}
+
+	for _, test := range tests {
+		_, err := interpreter.Evaluate(mustParse(t, test.rule), Context{"sku": "AB1234"})
+		if err == nil || err.Error() != test.message {
+			t.Errorf("Rule: %s\nExpected error %q, got %v", test.rule, test.message, err)
+		}
+	}
+}
+
+func TestFunctionTypeChecking(t *testing.T) {
+	tests := []struct {
+		rule    string
+		message string
+	}{
+		{"unknown_fn(name) = 1", "unknown function"},
+		{"lower(name, 'x') = 'bob'", "expects 1 arguments"},
+		{"abs('ten') < 5", "must be a number"},
+		{"lower(42) = 'bob'", "must be a string"},
+		{"sum(abs(x)) > 1", "must be a list"},
+		{"min() = 1", "at least 1 arguments"},
+	}
+
+	for _, test := range tests {
+		ast, err := parser.NewParser(parser.NewTokenizer(test.rule)).ParseRule()
+		if err != nil {
+			t.Fatalf("Rule: %s\nUnexpected parse error: %v", test.rule, err)
+		}
+
+		err = interpreter.Check(ast)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("Rule: %s\nExpected check error containing %q, but got: %v", test.rule, test.message, err)
+		}
+	}
+}
+
+func TestCustomFunctionRegistration(t *testing.T) {
+	err := interpreter.Functions.Register(interpreter.Function{
+		Name:    "is_corp_email",
+		Params:  []interpreter.Type{interpreter.TypeString},
+		Returns: interpreter.TypeBool,
+		Call: func(args []interface{}) (interface{}, error) {
+			return strings.HasSuffix(args[0].(string), "@corp.com"), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering function: %v", err)
+	}
+
+	runTestRule(t, "is_corp_email(email) AND age > 30", Context{"email": "a@corp.com", "age": 40}, true)
+	runTestRule(t, "NOT is_corp_email(email)", Context{"email": "a@gmail.com"}, true)
+
+	// Registering the same name twice is an error
+	err = interpreter.Functions.Register(interpreter.Function{
+		Name:   "is_corp_email",
+		Params: []interpreter.Type{interpreter.TypeString},
+		Call:   func(args []interface{}) (interface{}, error) { return false, nil },
+	})
+	if err == nil {
+		t.Errorf("Expected an error registering a duplicate function, but got none")
+	}
+}
@@ -1,12 +1,15 @@
 package Test
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
 	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/utils"
 )
 
 type Context = interpreter.Context // Alias for easier usage
@@ -132,3 +135,323 @@ func TestErrorHandling(t *testing.T) {
 		runTestRule(t, test.rule, test.context, test.expected)
 	}
 }
+
+func TestUnaryNegation(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"NOT (department = 'Sales')", Context{"department": "Marketing"}, true},
+		{"NOT (department = 'Sales')", Context{"department": "Sales"}, false},
+		{"!(age > 30)", Context{"age": 25}, true},
+		{"NOT age > 30", Context{"age": 32}, false},
+		{"NOT NOT age > 30", Context{"age": 32}, true},
+		{"age > 30 AND NOT department = 'Sales'", Context{"age": 32, "department": "HR"}, true},
+		{"NOT age > 30 OR salary > 50000", Context{"age": 32, "salary": 60000}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestInequality(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"department != 'Sales'", Context{"department": "Marketing"}, true},
+		{"department != 'Sales'", Context{"department": "Sales"}, false},
+		{"department <> 'Sales'", Context{"department": "HR"}, true},
+		{"age != 30", Context{"age": 30.0}, false},
+		{"age <> 30", Context{"age": 31.5}, true},
+		{"age != 30", Context{}, false},
+		{"age != 'thirty'", Context{"age": 30}, false}, // Incomparable types never match
+		{"age != 30 AND NOT department <> 'Sales'", Context{"age": 25, "department": "Sales"}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestArithmeticExpressions(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"salary * 12 > 600000", Context{"salary": 60000}, true},
+		{"salary * 12 > 600000", Context{"salary": 40000}, false},
+		{"(bonus + salary) / 2 >= 40000", Context{"bonus": 30000, "salary": 50000}, true},
+		{"(bonus + salary) / 2 >= 40000", Context{"bonus": 10000, "salary": 50000}, false},
+		{"experience % 5 = 0", Context{"experience": 10}, true},
+		{"experience % 5 = 0", Context{"experience": 7}, false},
+		{"salary - bonus * 2 = 30000", Context{"salary": 50000, "bonus": 10000}, true},
+		{"salary / 12 > 4000 AND age > 30", Context{"salary": 60000, "age": 32}, true},
+		{"salary * 12 > 600000", Context{}, false},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	tests := []struct {
+		rule    string
+		context Context
+	}{
+		{"salary / 0 > 1", Context{"salary": 100}},
+		{"salary / bonus > 1", Context{"salary": 100, "bonus": 0}},
+		{"experience % 0 = 0", Context{"experience": 10}},
+		{"department * 2 > 1", Context{"department": "Sales"}},
+	}
+
+	for _, test := range tests {
+		ast, err := parser.NewParser(parser.NewTokenizer(test.rule)).ParseRule()
+		if err != nil {
+			t.Fatalf("Rule: %s\nUnexpected parse error: %v", test.rule, err)
+		}
+
+		if _, err := interpreter.Evaluate(ast, test.context); err == nil {
+			t.Errorf("Rule: %s\nContext: %v\nExpected an evaluation error, but got none", test.rule, test.context)
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"score >= 4.5", Context{"score": 4.5}, true},
+		{"score >= 4.5", Context{"score": 4}, false},
+		{"balance > -100", Context{"balance": -50}, true},
+		{"balance > -100", Context{"balance": -150.5}, false},
+		{"balance = -100", Context{"balance": -100.0}, true},
+		{"revenue >= 1e6", Context{"revenue": 1000000}, true},
+		{"ratio < 2.5E-3", Context{"ratio": 0.001}, true},
+		{"age = 30", Context{"age": 30.0}, true},
+		{"age = 30.0", Context{"age": 30}, true},
+		{"age = 30", Context{"age": int64(30)}, true},
+		{"-balance > 100", Context{"balance": -200}, true},
+		{"balance - -100 = 0", Context{"balance": -100}, true},
+		{"price * 1.5 = 3", Context{"price": 2}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestAttributePaths(t *testing.T) {
+	user := map[string]interface{}{
+		"name": "Alice",
+		"address": map[string]interface{}{
+			"country": "US",
+			"zip":     "94105",
+		},
+	}
+	orders := []interface{}{
+		map[string]interface{}{"total": 120.5},
+		map[string]interface{}{"total": 80.0},
+	}
+
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"user.address.country = 'US'", Context{"user": user}, true},
+		{"user.address.country = 'CA'", Context{"user": user}, false},
+		{"user['name'] = 'Alice'", Context{"user": user}, true},
+		{"orders[0].total > 100", Context{"orders": orders}, true},
+		{"orders[1].total > 100", Context{"orders": orders}, false},
+		{"orders[0].total + orders[1].total = 200.5", Context{"orders": orders}, true},
+		{"orders[i].total = 80", Context{"orders": orders, "i": 1}, true},
+		{"scores[2] = 3", Context{"scores": []int{1, 2, 3}}, true},
+		{"user.address", Context{"user": user}, true},
+
+		// Missing intermediate keys and out of range indexes resolve to a missing value
+		{"user.phone.number = '555'", Context{"user": user}, false},
+		{"orders[5].total > 0", Context{"orders": orders}, false},
+		{"user.name.first = 'A'", Context{"user": user}, false},
+		{"NOT user.phone.number = '555'", Context{"user": user}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestSetMembership(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"country IN ['US', 'CA', 'MX']", Context{"country": "CA"}, true},
+		{"country IN ['US', 'CA', 'MX']", Context{"country": "FR"}, false},
+		{"country NOT IN ['US', 'CA', 'MX']", Context{"country": "FR"}, true},
+		{"country NOT IN ['US', 'CA', 'MX']", Context{"country": "US"}, false},
+		{"age IN [30, 40, 50]", Context{"age": 40}, true},
+		{"age IN [30, 40, 50]", Context{"age": 40.0}, true},
+		{"age IN [30, 40, 50]", Context{"age": "40"}, false},
+		{"level IN [base + 1, base + 2]", Context{"level": 7, "base": 5}, true},
+		{"timeout IN [1h, 2h]", Context{"timeout": time.Hour}, true},
+		{"timeout IN [1h, 2h]", Context{"timeout": 90 * time.Minute}, false},
+		{"timeout NOT IN [30m, 1h]", Context{"timeout": 2 * time.Hour}, true},
+		{"'admin' IN roles", Context{"roles": []interface{}{"user", "admin"}}, true},
+		{"'admin' IN roles", Context{"roles": []string{"user"}}, false},
+		{"country IN []", Context{"country": "US"}, false},
+		{"country IN ['US']", Context{}, false},
+		{"country NOT IN ['US']", Context{}, false},
+		{"NOT country IN ['US'] AND age > 30", Context{"country": "FR", "age": 32}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestLargeSetMembershipPerformance(t *testing.T) {
+	values := make([]string, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		values = append(values, fmt.Sprintf("'SKU%d'", i))
+	}
+	rule := "sku IN [" + strings.Join(values, ", ") + "]"
+
+	ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 10000; i++ {
+		if !interpreter.Interpret(ast, Context{"sku": "SKU4999"}) {
+			t.Fatalf("Expected SKU4999 to be a member of the list")
+		}
+	}
+	fmt.Printf("Performance test for 10000 lookups in a 5000 element list took: %s\n", time.Since(start))
+}
+
+func TestStringMatching(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"email ENDS_WITH '@corp.com'", Context{"email": "bob@corp.com"}, true},
+		{"email ENDS_WITH '@corp.com'", Context{"email": "bob@gmail.com"}, false},
+		{"name STARTS_WITH 'Jo'", Context{"name": "John"}, true},
+		{"name CONTAINS 'oh'", Context{"name": "John"}, true},
+		{"tags CONTAINS 'vip'", Context{"tags": []interface{}{"new", "vip"}}, true},
+		{"tags CONTAINS 'vip'", Context{"tags": []interface{}{"new"}}, false},
+		{"name LIKE 'Jo%'", Context{"name": "Joanna"}, true},
+		{"name LIKE 'Jo%'", Context{"name": "Bob"}, false},
+		{"code LIKE 'A_C'", Context{"code": "ABC"}, true},
+		{"code LIKE 'A_C'", Context{"code": "ABBC"}, false},
+		{"discount LIKE '100\\%'", Context{"discount": "100%"}, true},
+		{"discount LIKE '100\\%'", Context{"discount": "1000"}, false},
+		{"name LIKE 'a.c'", Context{"name": "abc"}, false}, // Regexp metacharacters are literal in LIKE
+		{"sku MATCHES '^A[0-9]{4}$'", Context{"sku": "A1234"}, true},
+		{"sku MATCHES '^A[0-9]{4}$'", Context{"sku": "A123"}, false},
+		{"sku MATCHES '^A'", Context{"sku": 1234}, false},
+		{"NOT email ENDS_WITH '@corp.com' AND age > 30", Context{"email": "x@y.com", "age": 40}, true},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	rules := []string{
+		"sku MATCHES '^A[0-9'",
+		"sku MATCHES pattern",
+		"name LIKE other_name",
+		"sku MATCHES '" + strings.Repeat("a", 2000) + "'",
+		"sku MATCHES '((a{100}){100}){100}'",
+	}
+
+	for _, rule := range rules {
+		_, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+		if err == nil {
+			t.Errorf("Rule: %.60s\nExpected a parse error for an invalid pattern, but got none", rule)
+		}
+	}
+}
+
+func TestBooleanAndNullSemantics(t *testing.T) {
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"is_active = true", Context{"is_active": true}, true},
+		{"is_active = true", Context{"is_active": false}, false},
+		{"is_active = false", Context{"is_active": false}, true},
+		{"is_active != true", Context{"is_active": false}, true},
+		{"is_active", Context{"is_active": true}, true},
+		{"is_active", Context{"is_active": false}, false},
+		{"NOT is_active", Context{"is_active": false}, true},
+		{"is_active AND age > 30", Context{"is_active": true, "age": 40}, true},
+		{"user.verified", Context{"user": map[string]interface{}{"verified": false}}, false},
+		{"is_active = 'true'", Context{"is_active": true}, false},
+		{"manager = null", Context{"manager": nil}, true},
+		{"manager = null", Context{}, true},
+		{"manager = null", Context{"manager": "Alice"}, false},
+		{"manager != null", Context{"manager": "Alice"}, true},
+		{"manager != null", Context{}, false},
+		{"null = manager", Context{}, true},
+		{"flag IN [true]", Context{"flag": true}, true},
+		{"manager IN [null, 'Bob']", Context{"manager": nil}, true},
+		{"manager IN [null, 'Bob']", Context{}, true},
+		{"manager IN [null, 'Bob']", Context{"manager": "Bob"}, true},
+		{"manager IN [null, 'Bob']", Context{"manager": "Alice"}, false},
+		{"manager IN ['Bob']", Context{"manager": nil}, false},
+		{"manager NOT IN [null]", Context{"manager": nil}, false},
+		{"manager NOT IN [null]", Context{"manager": "Alice"}, true},
+		{"true", Context{}, true},
+		{"false OR null", Context{}, false},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
+
+func TestBooleanAndNullJSONRoundTrip(t *testing.T) {
+	rule := "is_active = true AND manager != null AND NOT role IN ['guest', 'banned']"
+	ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+
+	// Store and reload the AST the same way the rules table does
+	astJSON, err := json.Marshal(ast)
+	if err != nil {
+		t.Fatalf("Failed to marshal AST: %v", err)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(astJSON, &stored); err != nil {
+		t.Fatalf("Failed to unmarshal AST: %v", err)
+	}
+	loaded, err := utils.ConvertToASTNode(stored)
+	if err != nil {
+		t.Fatalf("Failed to convert stored AST: %v", err)
+	}
+
+	ctx := Context{"is_active": true, "manager": "Alice", "role": "admin"}
+	if !interpreter.Interpret(loaded, ctx) {
+		t.Errorf("Expected reloaded AST to match %v", ctx)
+	}
+	ctx = Context{"is_active": true, "role": "admin"}
+	if interpreter.Interpret(loaded, ctx) {
+		t.Errorf("Expected reloaded AST not to match %v", ctx)
+	}
+}
@@ -0,0 +1,135 @@
+package Test
+
+import (
+	"testing"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/optimizer"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Rules exercising every rewrite, with the rule they optimize to and the
+// first rewrite that applies
+var optimizerTests = []struct {
+	rule     string
+	expected string
+	rewrite  string
+}{
+	// Constant folding
+	{"age > 10 + 8", "age > 18", optimizer.ConstantFolding},
+	{"price * 1.5 > 3 * 2", "price * 1.5 > 6", optimizer.ConstantFolding},
+	{"salary > 1000 * 1000", "salary > 1000000", optimizer.ConstantFolding},
+	{"age > -(5)", "age > -5", optimizer.ConstantFolding},
+	{"1 = 1 AND age > 30", "age > 30", optimizer.ConstantFolding},
+	{"age > 30 OR 2 > 3", "age > 30", optimizer.ConstantFolding},
+	{"age > 30 AND 'a' = 'b'", "false", optimizer.ConstantFolding},
+	{"age > 30 OR true", "true", optimizer.ConstantFolding},
+	{"'a' IN ['a', 'b'] AND x = 1", "x = 1", optimizer.ConstantFolding},
+	{"NOT false AND x = 1", "x = 1", optimizer.ConstantFolding},
+	{"null OR x = 1", "x = 1", optimizer.ConstantFolding},
+	{"x / 0 > 1", "x / 0 > 1", ""},
+	{"1 / 0 > 1", "1 / 0 > 1", ""},
+	{"1 = 'a' OR x = 1", "1 = 'a' OR x = 1", ""},
+	{"-5", "-5", ""},
+	// Double negation
+	{"NOT NOT active", "active", optimizer.DoubleNegation},
+	{"NOT (NOT (NOT active))", "NOT active", optimizer.DoubleNegation},
+	// Idempotence
+	{"a = 1 AND b = 2 AND a = 1.0", "a = 1 AND b = 2", optimizer.Idempotence},
+	{"a = 1 OR (b = 2 OR a = 1)", "a = 1 OR b = 2", optimizer.Idempotence},
+	// Complement
+	{"active AND x = 1 AND NOT active", "false", optimizer.Complement},
+	{"NOT (a = 1) OR a = 1", "true", optimizer.Complement},
+	// Absorption
+	{"a = 1 OR (a = 1 AND b = 2)", "a = 1", optimizer.Absorption},
+	{"(a = 1 AND b = 2) OR a = 1", "a = 1", optimizer.Absorption},
+	{"a = 1 AND (b = 2 OR a = 1)", "a = 1", optimizer.Absorption},
+	{"(a = 1 AND b = 2) OR (b = 2 AND a = 1 AND c = 3)", "a = 1 AND b = 2", optimizer.Absorption},
+	// Range merging
+	{"age > 18 AND age > 21", "age > 21", optimizer.RangeMerging},
+	{"age > 18 OR age > 21", "age > 18", optimizer.RangeMerging},
+	{"age >= 21 AND age > 21", "age > 21", optimizer.RangeMerging},
+	{"age >= 21 OR age > 21", "age >= 21", optimizer.RangeMerging},
+	{"age < 65 AND x = 1 AND 60 > age", "60 > age AND x = 1", optimizer.RangeMerging},
+	{"age > 18 AND age < 65 AND age >= 21", "age >= 21 AND age < 65", optimizer.RangeMerging},
+	{"age > 65 AND age < 18", "false", optimizer.RangeMerging},
+	{"age > 18 AND age <= 18", "false", optimizer.RangeMerging},
+	{"user.age > 18 AND user.age > 21 AND age > 30", "age > 30 AND user.age > 21", optimizer.RangeMerging},
+	{"age >= 18 AND age <= 18", "age >= 18 AND age <= 18", ""},
+	{"age > 18 AND age > '21'", "age > 18 AND age > '21'", ""},
+	// Reordering
+	{"len(name) > 3 AND age > 30", "age > 30 AND len(name) > 3", optimizer.Reordering},
+	{"name MATCHES '^A' AND age > 30", "age > 30 AND name MATCHES '^A'", optimizer.Reordering},
+	{"x / y > 1 AND age > 30 AND len(name) > 3", "age > 30 AND x / y > 1 AND len(name) > 3", optimizer.Reordering},
+	{"age > 30 AND name MATCHES '^A' AND x / y > 1", "age > 30 AND name MATCHES '^A' AND x / y > 1", ""},
+	{"age > 30 AND dept = 'Sales'", "age > 30 AND dept = 'Sales'", ""},
+}
+
+func TestOptimize(t *testing.T) {
+	for _, tt := range optimizerTests {
+		ast := mustParse(t, tt.rule)
+		before := parser.Format(ast)
+
+		result := optimizer.Optimize(ast)
+		if got := parser.Format(result.After); got != tt.expected {
+			t.Errorf("Rule: %s\nExpected: %s\nGot: %s", tt.rule, tt.expected, got)
+		}
+		if result.Before != ast || parser.Format(ast) != before {
+			t.Errorf("Rule: %s\nExpected the original AST to be kept unchanged", tt.rule)
+		}
+
+		if tt.rewrite == "" {
+			if result.Changed() {
+				t.Errorf("Rule: %s\nExpected no rewrites, got %v", tt.rule, result.Rewrites)
+			}
+		} else if len(result.Rewrites) == 0 || result.Rewrites[0] != tt.rewrite {
+			t.Errorf("Rule: %s\nExpected %s first, got %v", tt.rule, tt.rewrite, result.Rewrites)
+		}
+
+		// The optimized rule must parse back to itself
+		if formatted := parser.Format(result.After); !parser.Equal(mustParse(t, formatted), result.After) {
+			t.Errorf("Rule: %s\nOptimized rule %s does not round trip", tt.rule, formatted)
+		}
+	}
+}
+
+// Test that folded constants are stored the way the rule would write them
+func TestConstantFoldingValue(t *testing.T) {
+	result := optimizer.Optimize(mustParse(t, "salary > 1000 * 1000 AND rate < 1 / 10000000"))
+	if got := result.After.Left.Right.Value; got != "1000000" {
+		t.Errorf("Expected 1000 * 1000 to fold to 1000000, got %s", got)
+	}
+	if got := result.After.Right.Right.Value; got != "0.0000001" {
+		t.Errorf("Expected 1 / 10000000 to fold to 0.0000001, got %s", got)
+	}
+}
+
+// Test that optimized rules give the same results as the original ones, and
+// only fail where the original ones do
+func TestOptimizeKeepsResults(t *testing.T) {
+	contexts := []Context{
+		{},
+		{"age": 17, "a": 1, "b": 2, "c": 3, "x": 1, "y": 0, "active": true, "name": "Alice", "price": 2},
+		{"age": 18, "a": 2, "b": 2, "x": 6, "y": 3, "active": false, "name": "Al", "user": map[string]interface{}{"age": 22}},
+		{"age": 21.5, "a": 1, "x": "one", "y": 2, "name": "Bob", "user": map[string]interface{}{"age": 19}},
+		{"age": 65, "a": "1", "b": true, "x": nil, "active": "yes", "dept": "Sales", "price": "3"},
+		{"age": "NaN", "a": []interface{}{1}, "user": []interface{}{1}},
+		{"age": "thirty", "x": 12, "y": 4, "name": 7},
+	}
+
+	for _, tt := range optimizerTests {
+		ast := mustParse(t, tt.rule)
+		optimized := mustParse(t, parser.Format(optimizer.Optimize(ast).After))
+
+		for _, context := range contexts {
+			want, wantErr := interpreter.Evaluate(ast, context)
+			got, gotErr := interpreter.Evaluate(optimized, context)
+			if gotErr != nil && wantErr == nil {
+				t.Errorf("Rule: %s\nContext: %v\nOptimized rule fails: %v", tt.rule, context, gotErr)
+			}
+			if wantErr == nil && got != want {
+				t.Errorf("Rule: %s\nContext: %v\nExpected: %v, but got: %v", tt.rule, context, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package Test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+)
+
+// Helper function to parse a rule and return its diagnostics
+func parseDiagnostics(t *testing.T, rule string) parser.Diagnostics {
+	_, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err == nil {
+		return nil
+	}
+
+	var diagnostics parser.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Rule: %s\nExpected Diagnostics error, got %T: %v", rule, err, err)
+	}
+	return diagnostics
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	tests := []struct {
+		rule   string
+		code   string
+		line   int
+		column int
+		start  int
+		end    int
+	}{
+		{"", parser.CodeEmptyInput, 1, 1, 0, 0},
+		{"age > 30 $ foo", parser.CodeIllegalCharacter, 1, 10, 9, 10},
+		{"department = 'Sales", parser.CodeUnterminatedString, 1, 14, 13, 19},
+		{"age >", parser.CodeUnexpectedEnd, 1, 6, 5, 5},
+		{"(age > 30", parser.CodeUnexpectedEnd, 1, 10, 9, 9},
+		{"age > 30 and salary > 10", parser.CodeTrailingInput, 1, 10, 9, 12},
+		{"age > 30 AND\n  salary >> 10", parser.CodeUnexpectedToken, 2, 11, 23, 24},
+		{"sku MATCHES '[0-9'", parser.CodeInvalidPattern, 1, 13, 12, 18},
+		{"NOT ()", parser.CodeUnexpectedToken, 1, 6, 5, 6},
+		{"NOT ($)", parser.CodeIllegalCharacter, 1, 6, 5, 6},
+		{"NOT (a >)", parser.CodeUnexpectedToken, 1, 9, 8, 9},
+		{"-()", parser.CodeUnexpectedToken, 1, 3, 2, 3},
+		{"-($)", parser.CodeIllegalCharacter, 1, 3, 2, 3},
+	}
+
+	for _, test := range tests {
+		diagnostics := parseDiagnostics(t, test.rule)
+		if len(diagnostics) != 1 {
+			t.Errorf("Rule: %q\nExpected 1 diagnostic, got %v", test.rule, diagnostics)
+			continue
+		}
+
+		d := diagnostics[0]
+		if d.Code != test.code || d.Line != test.line || d.Column != test.column || d.Start != test.start || d.End != test.end {
+			t.Errorf("Rule: %q\nExpected %s at %d:%d [%d,%d), got %s at %d:%d [%d,%d): %s",
+				test.rule, test.code, test.line, test.column, test.start, test.end,
+				d.Code, d.Line, d.Column, d.Start, d.End, d.Message)
+		}
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	rule := "age >> 30 AND (department = OR x = 1) AND name = 'Bob AND"
+	diagnostics := parseDiagnostics(t, rule)
+
+	expected := []string{parser.CodeUnexpectedToken, parser.CodeUnexpectedToken, parser.CodeUnterminatedString}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Rule: %s\nExpected %d diagnostics, got %d: %v", rule, len(expected), len(diagnostics), diagnostics)
+	}
+	for i, code := range expected {
+		if diagnostics[i].Code != code {
+			t.Errorf("Diagnostic %d: expected code %s, got %s (%s)", i, code, diagnostics[i].Code, diagnostics[i].Message)
+		}
+	}
+}
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		rule       string
+		suggestion string
+	}{
+		{"a = 1 & b = 2", "use AND or && for a logical and"},
+		{"a = 1 or b = 2", "keywords are uppercase: use OR"},
+		{"(a = 1", "add a closing ')'"},
+		{"a == 1", "use a single '=' to compare values"},
+	}
+
+	for _, test := range tests {
+		diagnostics := parseDiagnostics(t, test.rule)
+		if len(diagnostics) == 0 || diagnostics[0].Suggestion != test.suggestion {
+			t.Errorf("Rule: %s\nExpected suggestion %q, got %v", test.rule, test.suggestion, diagnostics)
+		}
+	}
+}
+
+// Helper function to collect the source text of every node in the AST
+func collectSpans(rule string, node *parser.Node, spans map[string]bool) {
+	parser.Inspect(node, func(n *parser.Node) bool {
+		if n != nil {
+			spans[rule[n.Start:n.End]] = true
+		}
+		return true
+	})
+}
+
+func TestNodeSpans(t *testing.T) {
+	rule := "(age > 30 AND NOT dept = 'Sales') OR lower(name) IN ['a', 'b'] OR orders[0].total >= -5"
+	ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+
+	if ast.Start != 0 || ast.End != len(rule) {
+		t.Errorf("Expected root span [0,%d), got [%d,%d)", len(rule), ast.Start, ast.End)
+	}
+
+	spans := map[string]bool{}
+	collectSpans(rule, ast, spans)
+
+	expected := []string{
+		"(age > 30 AND NOT dept = 'Sales')",
+		"age > 30",
+		"NOT dept = 'Sales'",
+		"'Sales'",
+		"lower(name) IN ['a', 'b']",
+		"lower(name)",
+		"name",
+		"['a', 'b']",
+		"orders[0].total >= -5",
+		"orders[0].total",
+		"orders[0]",
+		"total",
+		"-5",
+	}
+	for _, text := range expected {
+		if !spans[text] {
+			t.Errorf("Expected a node spanning %q", text)
+		}
+	}
+}
+
+// Helper function to parse a rule that is expected to be valid
+func mustParse(t *testing.T, rule string) *parser.Node {
+	ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err != nil {
+		t.Fatalf("Rule: %s\nUnexpected parse error: %v", rule, err)
+	}
+	return ast
+}
+
+func TestFormatCanonical(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{"age>30   AND  department='Sales'", "age > 30 AND department = 'Sales'"},
+		{"(age > 30) && (salary < 50000)", "age > 30 AND salary < 50000"},
+		{"a = 1 || b = 2 AND c = 3", "a = 1 OR b = 2 AND c = 3"},
+		{"(a = 1 OR b = 2) AND c = 3", "(a = 1 OR b = 2) AND c = 3"},
+		{"a = 1 OR (b = 2 OR c = 3)", "a = 1 OR (b = 2 OR c = 3)"},
+		{"((a = 1 OR b = 2) OR c = 3)", "a = 1 OR b = 2 OR c = 3"},
+		{"!(age > 30)", "NOT age > 30"},
+		{"NOT (a = 1 AND b = 2)", "NOT (a = 1 AND b = 2)"},
+		{"status <> \"closed\"", "status != 'closed'"},
+		{"name = \"O'Brien\"", "name = \"O'Brien\""},
+		{"price * (1 + tax) >= 100.50", "price * (1 + tax) >= 100.5"},
+		{"a - (b - c) = a - b - c", "a - (b - c) = a - b - c"},
+		{"-(5) < -x", "-(5) < -x"},
+		{"-(a + b) > 1e3", "-(a + b) > 1000"},
+		{"salary > 1500000", "salary > 1500000"},
+		{"salary > 1.5e6", "salary > 1500000"},
+		{"x > 0.0000001", "x > 0.0000001"},
+		{"a[9223372036854775807] = 1", "a[9223372036854776000] = 1"},
+		{"x < 1e300 AND x > 1e-300", "x < 1e+300 AND x > 1e-300"},
+		{"dept not in ('a','b')", ""},
+		{"dept NOT IN ['a','b', x]", "dept NOT IN ['a', 'b', x]"},
+		{"user.address['city'] STARTS_WITH lower( x )", "user.address['city'] STARTS_WITH lower(x)"},
+		{"created > now() - 30d AND active = true AND manager = null", "created > now() - 30d AND active = true AND manager = null"},
+		{"email LIKE '%@example.com'", "email LIKE '%@example.com'"},
+		{"(a = 1) = false", "a = 1 = false"},
+		{"false = (a = 1)", "false = (a = 1)"},
+	}
+
+	for _, tt := range tests {
+		ast, err := parser.NewParser(parser.NewTokenizer(tt.rule)).ParseRule()
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("Rule: %s\nExpected a parse error", tt.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Rule: %s\nUnexpected parse error: %v", tt.rule, err)
+		}
+		if got := parser.Format(ast); got != tt.expected {
+			t.Errorf("Rule: %s\nExpected: %s\nGot: %s", tt.rule, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	rules := []string{
+		"(age > 30 AND department = 'Sales') OR (age < 25 AND department = 'Marketing')",
+		"((age > 30 AND department = 'Marketing')) AND (salary > 20000 OR experience > 5)",
+		"NOT NOT a AND !(b OR c)",
+		"a * -b % 3 - -2 + +c / (d - e) > 0",
+		"- -x = 1",
+		"x IN [1, 2 + 3, (y)] = (z NOT IN [])",
+		"items[0].price * 2 <= max(a, b = c, [1, 2])",
+		"sku MATCHES '^[A-Z]{3}-\\d+$' OR name CONTAINS \"it's\"",
+		"due - now() < 1h30m AND date('2024-01-01') > created",
+		"1.5e-3 = 0.0015 AND 10 = 10.0",
+	}
+
+	for _, rule := range rules {
+		ast := mustParse(t, rule)
+		formatted := parser.Format(ast)
+		reparsed := mustParse(t, formatted)
+		if !parser.Equal(ast, reparsed) {
+			t.Errorf("Rule: %s\nFormatted: %s\nReparsed AST differs", rule, formatted)
+		}
+		if again := parser.Format(reparsed); again != formatted {
+			t.Errorf("Rule: %s\nFormat is not stable: %s then %s", rule, formatted, again)
+		}
+	}
+}
+
+func TestNodeJSONRoundTrip(t *testing.T) {
+	rules := []string{
+		"(age > 30 AND department = 'Sales') OR NOT active = true",
+		"email LIKE '%@corp.com' AND sku MATCHES '^[A-Z]+$'",
+		"dept IN ['a', 'b', x] AND max(a, -b) * 2 >= items[0].price",
+		"due - now() < 30d AND manager = null",
+	}
+
+	for _, rule := range rules {
+		ast := mustParse(t, rule)
+		data, err := json.Marshal(ast)
+		if err != nil {
+			t.Fatalf("Rule: %s\nUnexpected marshal error: %v", rule, err)
+		}
+
+		var decoded parser.Node
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Rule: %s\nUnexpected unmarshal error: %v", rule, err)
+		}
+		if !parser.Equal(ast, &decoded) {
+			t.Errorf("Rule: %s\nDecoded AST differs: %s", rule, data)
+		}
+		if decoded.Start != ast.Start || decoded.End != ast.End {
+			t.Errorf("Rule: %s\nExpected span [%d,%d), got [%d,%d)", rule, ast.Start, ast.End, decoded.Start, decoded.End)
+		}
+	}
+
+	// Derived values are rebuilt when decoding
+	var decoded parser.Node
+	data, _ := json.Marshal(mustParse(t, "x IN [1, 2] AND y LIKE 'a%'"))
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected unmarshal error: %v", err)
+	}
+	if decoded.Left.Right.Set == nil || decoded.Right.Pattern == nil {
+		t.Errorf("Expected the list set and LIKE pattern to be rebuilt")
+	}
+}
+
+func TestNodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		path string
+	}{
+		{`{"Type": "Bogus", "Value": "x"}`, ""},
+		{`{"Value": "x"}`, ""},
+		{`{"Type": "BinaryExpression", "Value": "=", "Left": {"Type": "Identifier", "Value": "a"}}`, ""},
+		{`{"Type": "Identifier", "Value": "a", "Left": {"Type": "Identifier", "Value": "b"}}`, ""},
+		{`{"Type": "LogicalAndExpression", "Value": "AND", "Left": {"Type": "Identifier", "Value": "a"}, "Right": {"Type": "BinaryExpression", "Value": "=", "Left": {"Type": "NumericLiteral", "Value": "abc"}, "Right": {"Type": "Identifier", "Value": "b"}}}`, "/Right/Left"},
+		{`{"Type": "CallExpression", "Value": "max", "Arguments": [{"Type": "Identifier", "Value": "a"}, {"Type": "Identifier", "Value": 5}]}`, "/Arguments/1"},
+		{`{"Type": "ListLiteral", "Elements": [null]}`, "/Elements/0"},
+		{`{"Type": "BinaryExpression", "Value": "MATCHES", "Left": {"Type": "Identifier", "Value": "a"}, "Right": {"Type": "StringLiteral", "Value": "[0-9"}}`, ""},
+		{`{"Type": "UnaryExpression", "Value": "NOT", "Left": {"Type": "BooleanLiteral", "Value": "yes"}}`, "/Left"},
+		{`{"Type": "UnaryExpression", "Value": "", "Left": {"Type": "BooleanLiteral", "Value": "true"}}`, ""},
+	}
+
+	for _, tt := range tests {
+		var node parser.Node
+		err := json.Unmarshal([]byte(tt.json), &node)
+
+		var nodeErr *parser.NodeError
+		if !errors.As(err, &nodeErr) {
+			t.Errorf("JSON: %s\nExpected a NodeError, got %T: %v", tt.json, err, err)
+			continue
+		}
+		if nodeErr.Path != tt.path {
+			t.Errorf("JSON: %s\nExpected path %q, got %q (%v)", tt.json, tt.path, nodeErr.Path, err)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	ast := mustParse(t, "a = 1 AND f(b, [c, 2]) OR NOT d")
+
+	var kinds []string
+	parser.Inspect(ast, func(n *parser.Node) bool {
+		if n == nil {
+			return false
+		}
+		kinds = append(kinds, n.Type.String())
+		// Skip the contents of lists
+		return n.Type != parser.ListLiteral
+	})
+
+	expected := "LogicalOrExpression LogicalAndExpression BinaryExpression Identifier NumericLiteral CallExpression Identifier ListLiteral UnaryExpression Identifier"
+	if got := strings.Join(kinds, " "); got != expected {
+		t.Errorf("Expected visit order:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Everything the parser produces is valid
+	for _, rule := range []string{
+		"(age > 30 AND department = 'Sales') OR NOT active",
+		"email LIKE '%@corp.com' AND tags CONTAINS 'vip' AND -x % 2 = 0",
+		"user.orders[0].total >= max(1, 2) AND dept NOT IN ['a', 'b']",
+	} {
+		if err := parser.Validate(mustParse(t, rule), parser.DefaultLimits); err != nil {
+			t.Errorf("Rule: %s\nUnexpected validation error: %v", rule, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		ast    *parser.Node
+		limits parser.Limits
+		path   string
+	}{
+		{"node count", mustParse(t, "a = 1 AND b = 2 AND c = 3"), parser.Limits{MaxNodes: 6}, "/Left/Right/Left"},
+		{"depth", mustParse(t, "a = 1 AND (b = 2 OR c = 3)"), parser.Limits{MaxDepth: 3}, "/Right/Left/Left"},
+		{"missing operand", &parser.Node{Type: parser.UnaryExpression, Value: "NOT"}, parser.DefaultLimits, ""},
+		{"unknown operator", &parser.Node{
+			Type: parser.AdditiveExpression, Value: "^",
+			Left:  &parser.Node{Type: parser.Identifier, Value: "a"},
+			Right: &parser.Node{Type: parser.Identifier, Value: "b"},
+		}, parser.DefaultLimits, ""},
+		{"property", &parser.Node{
+			Type: parser.MemberExpression, Value: ".",
+			Left:  &parser.Node{Type: parser.Identifier, Value: "a"},
+			Right: &parser.Node{Type: parser.NumericLiteral, Value: "1"},
+		}, parser.DefaultLimits, ""},
+		{"identifier", &parser.Node{
+			Type: parser.CallExpression, Value: "len",
+			Arguments: []*parser.Node{{Type: parser.Identifier, Value: "a b"}},
+		}, parser.DefaultLimits, "/Arguments/0"},
+	}
+
+	for _, tt := range tests {
+		err := parser.Validate(tt.ast, tt.limits)
+		var nodeErr *parser.NodeError
+		if !errors.As(err, &nodeErr) {
+			t.Errorf("%s: expected a NodeError, got %v", tt.name, err)
+			continue
+		}
+		if nodeErr.Path != tt.path {
+			t.Errorf("%s: expected path %q, got %q (%v)", tt.name, tt.path, nodeErr.Path, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		before   string
+		after    string
+		expected []parser.Difference
+	}{
+		{"age > 30 AND dept = 'Sales'", "age > 30 && dept = 'Sales'", nil},
+		{"age > 30 AND dept = 'Sales'", "age > 35 AND dept = 'Sales'", []parser.Difference{
+			{Path: "/Left/Right", Before: "30", After: "35"},
+		}},
+		{"age > 30 AND dept = 'Sales'", "age >= 30 AND dept IN ['Sales', 'HR']", []parser.Difference{
+			{Path: "/Left", Before: "age > 30", After: "age >= 30"},
+			{Path: "/Right", Before: "dept = 'Sales'", After: "dept IN ['Sales', 'HR']"},
+		}},
+		{"max(a, b) > 1", "max(a, c) > 1", []parser.Difference{
+			{Path: "/Left/Arguments/1", Before: "b", After: "c"},
+		}},
+		{"a = 1", "a = 1 OR b = 2", []parser.Difference{
+			{Path: "", Before: "a = 1", After: "a = 1 OR b = 2"},
+		}},
+	}
+
+	for _, tt := range tests {
+		got := parser.Diff(mustParse(t, tt.before), mustParse(t, tt.after))
+		if len(got) != len(tt.expected) {
+			t.Errorf("Diff %q -> %q\nExpected: %v\nGot: %v", tt.before, tt.after, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("Diff %q -> %q\nExpected: %v\nGot: %v", tt.before, tt.after, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		operator string
+		rules    []string
+		expected string
+	}{
+		{"OR", []string{"a = 1", "b = 2"}, "a = 1 OR b = 2"},
+		{"AND", []string{"a = 1", "b = 2 OR c = 3"}, "a = 1 AND (b = 2 OR c = 3)"},
+		{"or", []string{"a = 1 OR b = 2", "b = 2 || c = 3", "a = 1.0"}, "a = 1 OR b = 2 OR c = 3"},
+		{"AND", []string{"age > 30 AND dept = 'Sales'", "dept = \"Sales\" AND salary > 5000"}, "age > 30 AND dept = 'Sales' AND salary > 5000"},
+		{"OR", []string{"(x = 1 AND x = 1) OR y = 2", "NOT (z = 1 OR z = 1)"}, "x = 1 OR y = 2 OR NOT z = 1"},
+		{"AND", []string{"max(a, (b > 1 OR b > 1)) = 1"}, "max(a, b > 1) = 1"},
+		{"AND", []string{"a = 1", "a = 1"}, "a = 1"},
+	}
+
+	for _, tt := range tests {
+		rules := make([]*parser.Node, len(tt.rules))
+		for i, rule := range tt.rules {
+			rules[i] = mustParse(t, rule)
+		}
+		before := make([]string, len(rules))
+		for i, rule := range rules {
+			before[i] = parser.Format(rule)
+		}
+
+		combined, err := parser.Combine(tt.operator, rules...)
+		if err != nil {
+			t.Errorf("Combine %s %q: unexpected error: %v", tt.operator, tt.rules, err)
+			continue
+		}
+		if got := parser.Format(combined); got != tt.expected {
+			t.Errorf("Combine %s %q\nExpected: %s\nGot: %s", tt.operator, tt.rules, tt.expected, got)
+		}
+		for i, rule := range rules {
+			if parser.Format(rule) != before[i] {
+				t.Errorf("Combine %s %q modified rule %d", tt.operator, tt.rules, i+1)
+			}
+		}
+	}
+
+	if _, err := parser.Combine("XOR", mustParse(t, "a = 1")); err == nil {
+		t.Errorf("Expected an error for an unknown operator")
+	}
+	if _, err := parser.Combine("AND"); err == nil {
+		t.Errorf("Expected an error without rules")
+	}
+}
+
+func TestMostFrequentOperator(t *testing.T) {
+	tests := []struct {
+		rules    []string
+		expected string
+	}{
+		{[]string{"a = 1", "b = 2"}, "OR"},
+		{[]string{"a = 1 AND b = 2", "c = 3 OR d = 4"}, "OR"},
+		{[]string{"a = 1 AND b = 2 AND c = 3", "c = 3 OR d = 4"}, "AND"},
+		{[]string{"a = 1 OR (b = 2 AND c = 3 AND NOT (d = 4 AND e = 5))"}, "AND"},
+	}
+
+	for _, tt := range tests {
+		rules := make([]*parser.Node, len(tt.rules))
+		for i, rule := range tt.rules {
+			rules[i] = mustParse(t, rule)
+		}
+		if got := parser.MostFrequentOperator(rules...); got != tt.expected {
+			t.Errorf("MostFrequentOperator %q: expected %s, got %s", tt.rules, tt.expected, got)
+		}
+	}
+}
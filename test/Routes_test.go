@@ -3,15 +3,15 @@ package Test
 import (
 	"bytes"
 	"encoding/json"
-	"log"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
-	"github.com/joho/godotenv"
 	"github.com/yash7xm/Rule_Engine_with_AST/cmd/routes"
 	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
 )
 
 // Helper function to create a new HTTP request with JSON body
@@ -30,15 +30,8 @@ func newJSONRequest(t *testing.T, method, url string, body interface{}) *http.Re
 
 // Test for createRuleHandler
 func TestCreateRuleHandler(t *testing.T) {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
-
-	// Initialize the database connection
-	db.InitDB()
-	defer db.DB.Close() // Close the DB connection when the application shuts down
+	// Store rules in memory
+	routes.SetStore(db.NewMemoryStore())
 
 	// Mock request data
 	reqBody := map[string]string{"rule_string": "a = 1"}
@@ -60,7 +53,7 @@ func TestCreateRuleHandler(t *testing.T) {
 
 	// Check the response body for valid JSON
 	var response map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&response)
+	err := json.NewDecoder(rr.Body).Decode(&response)
 	if err != nil {
 		t.Errorf("Expected valid JSON response, got error: %v", err)
 	}
@@ -73,15 +66,8 @@ func TestCreateRuleHandler(t *testing.T) {
 
 // Test for combineRulesHandler
 func TestCombineRulesHandler(t *testing.T) {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
-
-	// Initialize the database connection
-	db.InitDB()
-	defer db.DB.Close() // Close the DB connection when the application shuts down
+	// Store rules in memory
+	routes.SetStore(db.NewMemoryStore())
 
 	// Mock request data
 	reqBody := map[string]interface{}{
@@ -105,7 +91,7 @@ func TestCombineRulesHandler(t *testing.T) {
 
 	// Check the response body for valid JSON
 	var response map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&response)
+	err := json.NewDecoder(rr.Body).Decode(&response)
 	if err != nil {
 		t.Errorf("Expected valid JSON response, got error: %v", err)
 	}
@@ -119,15 +105,8 @@ func TestCombineRulesHandler(t *testing.T) {
 
 // Test for evaluateRuleHandler
 func TestEvaluateRuleHandler(t *testing.T) {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
-
-	// Initialize the database connection
-	db.InitDB()
-	defer db.DB.Close() // Close the DB connection when the application shuts down
+	// Store rules in memory
+	routes.SetStore(db.NewMemoryStore())
 
 	// Mock request data, representing AST as a JSON-like map structure
 	reqBody := `{
@@ -178,7 +157,7 @@ func TestEvaluateRuleHandler(t *testing.T) {
 
 	// Check the response body for valid JSON
 	var response map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&response)
+	err := json.NewDecoder(rr.Body).Decode(&response)
 	if err != nil {
 		t.Errorf("Expected valid JSON response, got error: %v", err)
 	}
@@ -188,3 +167,358 @@ func TestEvaluateRuleHandler(t *testing.T) {
 		t.Errorf("Expected result true, got %v", response["result"])
 	}
 }
+
+// Test that createRuleHandler reports every parse error as a diagnostic
+func TestCreateRuleHandlerDiagnostics(t *testing.T) {
+	reqBody := map[string]string{"rule_string": "age >> 30 AND name = 'Bob"}
+
+	req := newJSONRequest(t, "POST", "/create_rule", reqBody)
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(routes.CreateRuleHandler)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+
+	var response struct {
+		Diagnostics []parser.Diagnostic `json:"diagnostics"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+
+	if len(response.Diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", response.Diagnostics)
+	}
+	if d := response.Diagnostics[1]; d.Code != parser.CodeUnterminatedString || d.Column != 22 {
+		t.Errorf("Expected unterminated string at column 22, got %+v", d)
+	}
+}
+
+// Test that function type errors are reported at the span of the bad argument
+func TestCreateRuleHandlerTypeErrorSpan(t *testing.T) {
+	reqBody := map[string]string{"rule_string": "age > 30 AND lower(42) = 'x'"}
+
+	req := newJSONRequest(t, "POST", "/create_rule", reqBody)
+	rr := httptest.NewRecorder()
+
+	handler := http.HandlerFunc(routes.CreateRuleHandler)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+
+	var response struct {
+		Diagnostics []parser.Diagnostic `json:"diagnostics"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected valid JSON response, got error: %v", err)
+	}
+
+	if len(response.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", response.Diagnostics)
+	}
+	if d := response.Diagnostics[0]; d.Code != parser.CodeTypeError || d.Start != 19 || d.End != 21 {
+		t.Errorf("Expected type error spanning [19,21), got %+v", d)
+	}
+}
+
+// Test that malformed ASTs are rejected with a JSON pointer to the bad node
+func TestEvaluateRuleHandlerInvalidAST(t *testing.T) {
+	identifier := func(name string) map[string]interface{} {
+		return map[string]interface{}{"Type": "Identifier", "Value": name}
+	}
+	number := map[string]interface{}{"Type": "NumericLiteral", "Value": "30"}
+
+	// A chain of NOTs deeper than the default limit
+	deep := map[string]interface{}{"Type": "BooleanLiteral", "Value": "true"}
+	for i := 0; i < parser.DefaultLimits.MaxDepth; i++ {
+		deep = map[string]interface{}{"Type": "UnaryExpression", "Value": "NOT", "Left": deep}
+	}
+
+	tests := []struct {
+		name string
+		ast  interface{}
+		path string
+	}{
+		{"missing right operand", map[string]interface{}{
+			"Type": "LogicalAndExpression", "Value": "AND",
+			"Left":  map[string]interface{}{"Type": "BinaryExpression", "Value": ">", "Left": identifier("age")},
+			"Right": identifier("active"),
+		}, "/Left"},
+		{"unknown operator", map[string]interface{}{
+			"Type": "LogicalOrExpression", "Value": "OR",
+			"Left":  identifier("active"),
+			"Right": map[string]interface{}{"Type": "BinaryExpression", "Value": "===", "Left": identifier("age"), "Right": number},
+		}, "/Right"},
+		{"wrong field type", map[string]interface{}{
+			"Type": "BinaryExpression", "Value": ">", "Left": "age", "Right": number,
+		}, "/Left"},
+		{"unknown node type", map[string]interface{}{
+			"Type": "CallExpression", "Value": "max",
+			"Arguments": []interface{}{number, map[string]interface{}{"Type": "Script", "Value": "x"}},
+		}, "/Arguments/1"},
+		{"unknown function", map[string]interface{}{
+			"Type": "BinaryExpression", "Value": "=",
+			"Left":  map[string]interface{}{"Type": "CallExpression", "Value": "exec", "Arguments": []interface{}{identifier("cmd")}},
+			"Right": number,
+		}, "/Left"},
+		{"too deep", deep, "/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left/Left"},
+		{"missing AST", nil, ""},
+	}
+
+	for _, tt := range tests {
+		reqBody := map[string]interface{}{"ast": tt.ast, "data": map[string]interface{}{"age": 35}}
+		req := newJSONRequest(t, "POST", "/evaluate_rule", reqBody)
+		rr := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(routes.EvaluateRuleHandler)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", tt.name, http.StatusBadRequest, status)
+		}
+
+		var response struct {
+			Error string  `json:"error"`
+			Path  *string `json:"path"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: expected valid JSON response, got error: %v", tt.name, err)
+		}
+		if response.Path == nil || *response.Path != tt.path {
+			t.Errorf("%s: expected path %q, got %v (%s)", tt.name, tt.path, response.Path, response.Error)
+		}
+	}
+}
+
+// Test that the router enforces methods and validates parameters before
+// touching the database
+func TestRulesRouting(t *testing.T) {
+	router := routes.NewRouter()
+
+	tests := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{"GET", "/create_rule", http.StatusMethodNotAllowed},
+		{"POST", "/ping", http.StatusMethodNotAllowed},
+		{"PATCH", "/rules/1/versions", http.StatusMethodNotAllowed},
+		{"POST", "/rules", http.StatusMethodNotAllowed},
+		{"GET", "/ping", http.StatusOK},
+		{"GET", "/rules/Not_A_Name", http.StatusBadRequest},
+		{"DELETE", "/rules/0", http.StatusBadRequest},
+		{"GET", "/rules?page=0", http.StatusBadRequest},
+		{"GET", "/rules?page_size=500", http.StatusBadRequest},
+		{"GET", "/rules?sort=-ast", http.StatusBadRequest},
+		{"GET", "/rules?created_after=yesterday", http.StatusBadRequest},
+		{"GET", "/rules?enabled=maybe", http.StatusBadRequest},
+		{"PUT", "/rules/1", http.StatusBadRequest},
+		{"GET", "/rules/1/versions/first", http.StatusBadRequest},
+		{"GET", "/rules/1/diff?from=1", http.StatusBadRequest},
+		{"POST", "/rules/1/rollback", http.StatusBadRequest},
+		{"DELETE", "/rules/1/versions", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(`{"rule_string": "age >"}`))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("%s %s: expected status code %d, got %d: %s", tt.method, tt.url, tt.status, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+// Test that rule metadata is validated before touching the database
+func TestRuleMetadataValidation(t *testing.T) {
+	router := routes.NewRouter()
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{"POST", "/create_rule", `{"rule_string": "age > 30", "name": "High Value"}`},
+		{"POST", "/create_rule", `{"rule_string": "age > 30", "name": "-leading-hyphen"}`},
+		{"POST", "/create_rule", `{"rule_string": "age > 30", "name": "42"}`},
+		{"POST", "/create_rule", `{"rule_string": "age > 30", "enabled": "yes"}`},
+		{"POST", "/combine_rules", `{"rules": ["a = 1"], "name": "` + strings.Repeat("a", 101) + `"}`},
+		{"PATCH", "/rules/1", `{"name": "under_score"}`},
+		{"PATCH", "/rules/1", `{"tags": "vip"}`},
+		{"POST", "/evaluate_rule", `{"rule_name": "a", "rule_ids": [1], "data": {}}`},
+		{"POST", "/evaluate_rule", `{"rule_names": ["a"], "version": 2, "data": {}}`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s %s %s: expected status code %d, got %d: %s", tt.method, tt.url, tt.body, http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+// Test creating, looking up, disabling and evaluating a named rule through
+// the router, with rules stored in memory
+func TestRuleLifecycle(t *testing.T) {
+	routes.SetStore(db.NewMemoryStore())
+	router := routes.NewRouter()
+
+	send := func(method, url, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("%s %s: expected valid JSON response, got error: %v", method, url, err)
+		}
+		data, _ := response["data"].(map[string]interface{})
+		return rr.Code, data
+	}
+
+	status, data := send("POST", "/create_rule", `{"rule_string": "age > 30", "name": "over-thirty", "tags": ["age", " age ", ""]}`)
+	if status != http.StatusOK || data["name"] != "over-thirty" {
+		t.Fatalf("Expected the rule to be created, got %d: %v", status, data)
+	}
+	if status, _ := send("POST", "/create_rule", `{"rule_string": "age > 40", "name": "over-thirty"}`); status != http.StatusConflict {
+		t.Errorf("Expected status code %d for a duplicate name, got %d", http.StatusConflict, status)
+	}
+
+	status, data = send("GET", "/rules/over-thirty", "")
+	rule, _ := data["rule"].(map[string]interface{})
+	if status != http.StatusOK || rule["rule_string"] != "age > 30" || len(rule["tags"].([]interface{})) != 1 {
+		t.Errorf("Expected to get the rule by name, got %d: %v", status, data)
+	}
+	if status, _ := send("GET", "/rules/under-thirty", ""); status != http.StatusNotFound {
+		t.Errorf("Expected status code %d for an unknown name, got %d", http.StatusNotFound, status)
+	}
+
+	evaluate := `{"rule_name": "over-thirty", "data": {"age": 35}}`
+	if status, data := send("POST", "/evaluate_rule", evaluate); status != http.StatusOK || data["result"] != true {
+		t.Errorf("Expected the rule to match, got %d: %v", status, data)
+	}
+
+	// Disabled rules evaluate to false
+	if status, data := send("PATCH", "/rules/over-thirty", `{"enabled": false}`); status != http.StatusOK {
+		t.Fatalf("Expected the rule to be disabled, got %d: %v", status, data)
+	}
+	if status, data := send("POST", "/evaluate_rule", evaluate); status != http.StatusOK || data["result"] != false || data["enabled"] != false {
+		t.Errorf("Expected a disabled rule not to match, got %d: %v", status, data)
+	}
+
+	status, data = send("GET", "/rules?enabled=false&tag=age", "")
+	if status != http.StatusOK || data["total"] != float64(1) {
+		t.Errorf("Expected one disabled rule tagged age, got %d: %v", status, data)
+	}
+}
+
+// Test combining stored rules and rule strings with a chosen operator
+func TestCombineRulesHandlerOperators(t *testing.T) {
+	routes.SetStore(db.NewMemoryStore())
+	router := routes.NewRouter()
+
+	send := func(method, url, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("%s %s: expected valid JSON response, got error: %v", method, url, err)
+		}
+		data, _ := response["data"].(map[string]interface{})
+		return rr.Code, data
+	}
+
+	send("POST", "/create_rule", `{"rule_string": "age > 30 AND dept = 'Sales'"}`)
+	send("POST", "/create_rule", `{"rule_string": "dept = 'Sales' AND salary > 5000", "name": "well-paid-sales"}`)
+
+	tests := []struct {
+		body     string
+		status   int
+		operator string
+		combined string
+	}{
+		{`{"rule_ids": [1], "rule_names": ["well-paid-sales"], "operator": "and"}`, http.StatusOK, "AND", "age > 30 AND dept = 'Sales' AND salary > 5000"},
+		{`{"rules": ["age > 30 AND dept = 'Sales'", "age < 20"], "rule_ids": [1], "operator": "most_frequent", "optimize": false}`, http.StatusOK, "AND", "age > 30 AND dept = 'Sales' AND age < 20"},
+		{`{"rules": ["a = 1", "b = 2", "a = 1"]}`, http.StatusOK, "OR", "a = 1 OR b = 2"},
+		{`{"rules": ["age > 30 AND dept = 'Sales'", "age < 20"], "operator": "and"}`, http.StatusOK, "AND", "false"},
+		{`{"rule_ids": [1], "operator": "XOR"}`, http.StatusBadRequest, "", ""},
+		{`{"rule_ids": [99]}`, http.StatusNotFound, "", ""},
+		{`{"rule_names": ["unknown-rule"]}`, http.StatusNotFound, "", ""},
+		{`{"operator": "AND"}`, http.StatusBadRequest, "", ""},
+	}
+
+	for _, tt := range tests {
+		status, data := send("POST", "/combine_rules", tt.body)
+		if status != tt.status {
+			t.Errorf("%s: expected status code %d, got %d: %v", tt.body, tt.status, status, data)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if data["operator"] != tt.operator || data["combined_rule"] != tt.combined {
+			t.Errorf("%s\nExpected: %s %s\nGot: %v %v", tt.body, tt.operator, tt.combined, data["operator"], data["combined_rule"])
+		}
+	}
+}
+
+// Test that created and combined rules are optimized, and that the response
+// shows the rule before optimization
+func TestRuleOptimization(t *testing.T) {
+	routes.SetStore(db.NewMemoryStore())
+	router := routes.NewRouter()
+
+	tests := []struct {
+		url           string
+		body          string
+		rule          string
+		original      string
+		optimizations []interface{}
+	}{
+		{"/create_rule", `{"rule_string": "age > 18 AND age > 10 + 11"}`, "age > 21", "age > 18 AND age > 10 + 11", []interface{}{"constant_folding", "range_merging"}},
+		{"/create_rule", `{"rule_string": "age > 18 AND age > 10 + 11", "optimize": false}`, "age > 18 AND age > 10 + 11", "age > 18 AND age > 10 + 11", []interface{}{}},
+		{"/create_rule", `{"rule_string": "age > 30"}`, "age > 30", "age > 30", []interface{}{}},
+		{"/combine_rules", `{"rules": ["a = 1", "a = 1 AND b = 2"]}`, "a = 1", "a = 1 OR a = 1 AND b = 2", []interface{}{"absorption"}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected status code %d, got %d: %s", tt.body, http.StatusOK, rr.Code, rr.Body.String())
+			continue
+		}
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: expected valid JSON response, got error: %v", tt.body, err)
+		}
+
+		rule := response.Data["rule_string"]
+		if tt.url == "/combine_rules" {
+			rule = response.Data["combined_rule"]
+		}
+		if rule != tt.rule || response.Data["original_rule"] != tt.original || response.Data["original_node"] == nil {
+			t.Errorf("%s\nExpected: %s from %s\nGot: %v from %v", tt.body, tt.rule, tt.original, rule, response.Data["original_rule"])
+		}
+		if !reflect.DeepEqual(response.Data["optimizations"], tt.optimizations) {
+			t.Errorf("%s: expected optimizations %v, got %v", tt.body, tt.optimizations, response.Data["optimizations"])
+		}
+	}
+}
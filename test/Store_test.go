@@ -0,0 +1,251 @@
+package Test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	db "github.com/yash7xm/Rule_Engine_with_AST/internal/database"
+)
+
+// Test that every store backend behaves the same. Postgres is only tested
+// when TEST_DATABASE_URL points at a database that may be written to.
+func TestRuleStore(t *testing.T) {
+	backends := map[string]func(t *testing.T) db.RuleStore{
+		"memory": func(t *testing.T) db.RuleStore {
+			return db.NewMemoryStore()
+		},
+		"sqlite": func(t *testing.T) db.RuleStore {
+			store, err := db.Open(db.Config{Driver: db.DriverSQLite, URL: filepath.Join(t.TempDir(), "rules.db"), AutoMigrate: true})
+			if err != nil {
+				t.Fatalf("Failed to open SQLite store: %v", err)
+			}
+			return store
+		},
+		"postgres": func(t *testing.T) db.RuleStore {
+			url := os.Getenv("TEST_DATABASE_URL")
+			if url == "" {
+				t.Skip("TEST_DATABASE_URL is not set")
+			}
+			store, err := db.Open(db.Config{Driver: db.DriverPostgres, URL: url, AutoMigrate: true})
+			if err != nil {
+				t.Fatalf("Failed to open Postgres store: %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			defer store.Close()
+			testRuleStore(t, store)
+		})
+	}
+}
+
+func testRuleStore(t *testing.T, store db.RuleStore) {
+	ast := []byte(`{"Type":"BooleanLiteral","Value":"true"}`)
+	create := func(ruleString string, metadata db.RuleMetadata) *db.Rule {
+		t.Helper()
+		rule, err := store.CreateRule(ruleString, ast, metadata, db.Change{Author: "alice"})
+		if err != nil {
+			t.Fatalf("Failed to create %q: %v", ruleString, err)
+		}
+		return rule
+	}
+
+	// Names are unique
+	suffix := time.Now().Format("150405.000000000")
+	adults := create("age >= 18", db.RuleMetadata{Name: "adults-" + suffix, Tags: []string{"age", "kyc"}, OwnerTeam: "risk", Enabled: true})
+	sales := create("dept = 'Sales'", db.RuleMetadata{Tags: []string{"dept"}, OwnerTeam: "growth-" + suffix})
+	if adults.Version != 1 || adults.Name != "adults-"+suffix || len(adults.Tags) != 2 || sales.Name != "" || sales.Enabled {
+		t.Errorf("Unexpected created rules: %+v, %+v", adults, sales)
+	}
+	if _, err := store.CreateRule("x = 1", ast, db.RuleMetadata{Name: adults.Name}, db.Change{}); !errors.Is(err, db.ErrDuplicateName) {
+		t.Errorf("Expected ErrDuplicateName, got %v", err)
+	}
+
+	// Lookups by id and name
+	if rule, err := store.GetRuleByName(adults.Name); err != nil || rule.ID != adults.ID || rule.RuleString != "age >= 18" {
+		t.Errorf("Expected to find rule %d by name, got %+v, %v", adults.ID, rule, err)
+	}
+	if _, err := store.GetRuleByName("missing-" + suffix); !errors.Is(err, db.ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound for an unknown name, got %v", err)
+	}
+	if _, err := store.GetRule(sales.ID + 1000); !errors.Is(err, db.ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound for an unknown id, got %v", err)
+	}
+
+	// Filters, sorting and pagination
+	enabled := true
+	tests := []struct {
+		query db.RuleQuery
+		want  []int
+	}{
+		{db.RuleQuery{Tag: "kyc", OwnerTeam: "risk", Limit: 10}, []int{adults.ID}},
+		{db.RuleQuery{OwnerTeam: "growth-" + suffix, Limit: 10}, []int{sales.ID}},
+		{db.RuleQuery{Search: "SALES", OwnerTeam: "growth-" + suffix, Limit: 10}, []int{sales.ID}},
+		{db.RuleQuery{Search: "age", OwnerTeam: "growth-" + suffix, Limit: 10}, []int{}},
+		{db.RuleQuery{Enabled: &enabled, Tag: "kyc", Limit: 10}, []int{adults.ID}},
+		{db.RuleQuery{CreatedAfter: timePtr(time.Now().Add(time.Hour)), Tag: "kyc", Limit: 10}, []int{}},
+		{db.RuleQuery{CreatedBefore: timePtr(time.Now().Add(time.Hour)), Tag: "kyc", Limit: 10}, []int{adults.ID}},
+	}
+	for _, tt := range tests {
+		rules, total, err := store.ListRules(tt.query)
+		if err != nil {
+			t.Fatalf("ListRules(%+v): %v", tt.query, err)
+		}
+		if ids := ruleIDs(rules); total != len(tt.want) || !equalInts(ids, tt.want) {
+			t.Errorf("ListRules(%+v): expected %v, got %v of %d", tt.query, tt.want, ids, total)
+		}
+	}
+
+	page, total, err := store.ListRules(db.RuleQuery{SortBy: "id", Descending: true, Limit: 1})
+	if err != nil || len(page) != 1 || total < 2 || page[0].ID != sales.ID {
+		t.Errorf("Expected the newest rule first, got %v of %d, %v", ruleIDs(page), total, err)
+	}
+
+	// Metadata updates do not add versions
+	description, disabled := "Adults only", false
+	rule, err := store.UpdateRuleMetadata(adults.ID, db.MetadataUpdate{Description: &description, Enabled: &disabled, Tags: []string{"age"}})
+	if err != nil || rule.Description != description || rule.Enabled || len(rule.Tags) != 1 || rule.Version != 1 || rule.Name != adults.Name {
+		t.Errorf("Unexpected metadata update: %+v, %v", rule, err)
+	}
+	if _, err := store.UpdateRuleMetadata(sales.ID, db.MetadataUpdate{Name: &adults.Name}); !errors.Is(err, db.ErrDuplicateName) {
+		t.Errorf("Expected ErrDuplicateName when renaming, got %v", err)
+	}
+
+	// Updates and rollbacks add versions
+	if rule, err = store.UpdateRule(adults.ID, "age >= 21", ast, db.Change{Author: "bob", Note: "Raise age"}); err != nil || rule.Version != 2 {
+		t.Fatalf("Unexpected update: %+v, %v", rule, err)
+	}
+	if rule, err = store.RollbackRule(adults.ID, 1, db.Change{}); err != nil || rule.Version != 3 || rule.RuleString != "age >= 18" {
+		t.Fatalf("Unexpected rollback: %+v, %v", rule, err)
+	}
+	if _, err := store.RollbackRule(adults.ID, 9, db.Change{}); !errors.Is(err, db.ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+
+	versions, err := store.ListRuleVersions(adults.ID)
+	if err != nil || len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d, %v", len(versions), err)
+	}
+	if versions[0].Author != "alice" || versions[1].ChangeNote != "Raise age" || versions[2].ChangeNote != "Rollback to version 1" {
+		t.Errorf("Unexpected version history: %+v", versions)
+	}
+	if version, err := store.GetRuleVersion(adults.ID, 2); err != nil || version.RuleString != "age >= 21" || string(version.AST) != string(ast) {
+		t.Errorf("Unexpected version 2: %+v, %v", version, err)
+	}
+
+	// Deleting a rule deletes its history
+	if err := store.DeleteRule(adults.ID); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if err := store.DeleteRule(adults.ID); !errors.Is(err, db.ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound deleting twice, got %v", err)
+	}
+	if _, err := store.ListRuleVersions(adults.ID); !errors.Is(err, db.ErrRuleNotFound) {
+		t.Errorf("Expected the history to be deleted, got %v", err)
+	}
+	store.DeleteRule(sales.ID)
+}
+
+// Test applying and reverting the embedded SQLite migrations
+func TestMigrations(t *testing.T) {
+	config := db.Config{Driver: db.DriverSQLite, URL: filepath.Join(t.TempDir(), "rules.db")}
+	store, err := db.OpenSQL(config)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	defer store.Close()
+
+	latest, err := store.LatestSchemaVersion()
+	if err != nil || latest < 3 {
+		t.Fatalf("Expected at least 3 migrations, got %d, %v", latest, err)
+	}
+	checkVersion := func(want uint) {
+		t.Helper()
+		if version, dirty, err := store.SchemaVersion(); err != nil || dirty || version != want {
+			t.Errorf("Expected schema version %d, got %d (dirty %t), %v", want, version, dirty, err)
+		}
+	}
+
+	// A new database must be migrated before the server uses it
+	checkVersion(0)
+	if err := store.CheckSchema(); !errors.Is(err, db.ErrSchemaMismatch) {
+		t.Errorf("Expected ErrSchemaMismatch before migrating, got %v", err)
+	}
+	if _, err := db.Open(config); !errors.Is(err, db.ErrSchemaMismatch) {
+		t.Errorf("Expected Open to check the schema, got %v", err)
+	}
+
+	if err := store.MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	checkVersion(latest)
+	if err := store.CheckSchema(); err != nil {
+		t.Errorf("Expected the migrated schema to be current, got %v", err)
+	}
+	if err := store.MigrateUp(); err != nil {
+		t.Errorf("Expected migrating a current schema to do nothing, got %v", err)
+	}
+
+	// Reverting the metadata migration keeps the rules
+	rule, err := store.CreateRule("age > 30", []byte(`{}`), db.RuleMetadata{Name: "over-thirty", Enabled: true}, db.Change{})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if err := store.MigrateDown(1); err != nil {
+		t.Fatalf("Failed to migrate down: %v", err)
+	}
+	checkVersion(latest - 1)
+	if _, err := store.GetRule(rule.ID); err == nil {
+		t.Errorf("Expected the metadata columns to be dropped")
+	}
+	if err := store.MigrateDown(int(latest)); err == nil {
+		t.Errorf("Expected an error reverting more migrations than were applied")
+	}
+	checkVersion(latest - 1)
+
+	if err := store.MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate up again: %v", err)
+	}
+	if got, err := store.GetRule(rule.ID); err != nil || got.RuleString != "age > 30" || got.Name != "" || !got.Enabled {
+		t.Errorf("Expected the rule to survive with default metadata, got %+v, %v", got, err)
+	}
+
+	if err := store.MigrateDown(0); err != nil {
+		t.Fatalf("Failed to revert every migration: %v", err)
+	}
+	checkVersion(0)
+}
+
+// Helper function to take the address of a time
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// Helper function to list the ids of rules
+func ruleIDs(rules []db.Rule) []int {
+	ids := []int{}
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+// Helper function to compare two lists of ints
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
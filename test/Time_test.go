@@ -0,0 +1,41 @@
+package Test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+)
+
+func TestDateTimeComparisons(t *testing.T) {
+	restore := interpreter.SetClock(func() time.Time {
+		return time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	tests := []struct {
+		rule     string
+		context  Context
+		expected bool
+	}{
+		{"signup_date > date('2024-01-01')", Context{"signup_date": "2024-03-10T08:00:00Z"}, true},
+		{"signup_date > date('2024-01-01')", Context{"signup_date": "2023-12-31"}, false},
+		{"signup_date = date('2024-01-01T00:00:00Z')", Context{"signup_date": "2024-01-01"}, true},
+		{"signup_date <= now()", Context{"signup_date": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"now() - last_login > 30d", Context{"last_login": "2024-05-01T00:00:00Z"}, true},
+		{"now() - last_login > 30d", Context{"last_login": "2024-06-10T00:00:00Z"}, false},
+		{"now() - last_login > 1w", Context{"last_login": "2024-06-10T00:00:00+02:00"}, false},
+		{"last_login + 12h > now()", Context{"last_login": "2024-06-15T01:00:00Z"}, true},
+		{"date(expires_at) - 1d < now()", Context{"expires_at": "2024-06-16T06:00:00Z"}, true},
+		{"session_length >= 1h30m", Context{"session_length": "95m"}, true},
+		{"session_length < 500ms", Context{"session_length": "2s"}, false},
+		{"duration(timeout) * 2 = 1m", Context{"timeout": "30s"}, true},
+		{"30d / 1d = 30", Context{}, true},
+		{"signup_date > date('2024-01-01')", Context{"signup_date": "not a date"}, false},
+		{"signup_date > date('2024-01-01')", Context{}, false},
+	}
+
+	for _, test := range tests {
+		runTestRule(t, test.rule, test.context, test.expected)
+	}
+}
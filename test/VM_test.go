@@ -0,0 +1,180 @@
+package Test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/interpreter"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/parser"
+	"github.com/yash7xm/Rule_Engine_with_AST/internal/vm"
+)
+
+// Helper function to compile a rule string to bytecode
+func mustCompile(t testing.TB, rule string) *vm.Program {
+	t.Helper()
+	ast, err := parser.NewParser(parser.NewTokenizer(rule)).ParseRule()
+	if err != nil {
+		t.Fatalf("Error parsing rule %q: %v", rule, err)
+	}
+	program, err := vm.Compile(ast)
+	if err != nil {
+		t.Fatalf("Error compiling rule %q: %v", rule, err)
+	}
+	return program
+}
+
+// Test that programs give the same results as Interpret and the same errors
+// as Evaluate
+func TestProgramMatchesInterpreter(t *testing.T) {
+	restore := interpreter.SetClock(func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) })
+	defer restore()
+
+	for _, rule := range differentialRules {
+		ast := mustParse(t, rule)
+		program := mustCompile(t, rule)
+
+		for _, context := range differentialContexts {
+			want := interpreter.Interpret(ast, context)
+			_, wantErr := interpreter.Evaluate(ast, context)
+			got, gotErr := program.Run(context)
+			if got != want || fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("Rule: %s\nContext: %v\nExpected: %v, %v\nGot: %v, %v\n%s", rule, context, want, wantErr, got, gotErr, program.Disassemble())
+			}
+		}
+	}
+
+	program, err := vm.Compile(nil)
+	if err != nil {
+		t.Fatalf("Error compiling a missing rule: %v", err)
+	}
+	if result, err := program.Run(Context{}); result || err != nil {
+		t.Errorf("Expected a missing rule to be false, got %v, %v", result, err)
+	}
+}
+
+// Test that programs agree with the interpreter on hand-built trees: invalid
+// literals, lists without a hash set and conditions used as values
+func TestProgramMatchesInterpreterOnBuiltTrees(t *testing.T) {
+	identifier := func(name string) *parser.Node { return &parser.Node{Type: parser.Identifier, Value: name} }
+	number := func(value string) *parser.Node { return &parser.Node{Type: parser.NumericLiteral, Value: value} }
+	nodes := []*parser.Node{
+		{Type: parser.BinaryExpression, Value: ">", Left: identifier("age"), Right: number("1e400x")},
+		{Type: parser.BinaryExpression, Value: "IN", Left: identifier("age"), Right: &parser.Node{
+			Type: parser.ListLiteral, Value: "[]", Elements: []*parser.Node{number("32"), identifier("min_age")}}},
+		{Type: parser.BinaryExpression, Value: "=", Left: &parser.Node{Type: parser.LogicalAndExpression,
+			Left: identifier("active"), Right: identifier("verified")}, Right: identifier("age")},
+		{Type: parser.UnaryExpression, Value: "-", Left: &parser.Node{Type: parser.DurationLiteral, Value: "3x"}},
+	}
+
+	for _, node := range nodes {
+		program, err := vm.Compile(node)
+		if err != nil {
+			t.Fatalf("Error compiling %s: %v", parser.Format(node), err)
+		}
+		for _, context := range differentialContexts {
+			want, wantErr := interpreter.Evaluate(node, context)
+			got, gotErr := program.Run(context)
+			if got != want || fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("Rule: %s\nContext: %v\nExpected: %v, %v\nGot: %v, %v", parser.Format(node), context, want, wantErr, got, gotErr)
+			}
+		}
+	}
+}
+
+// Test that trees the parser cannot produce are rejected
+func TestCompileRejectsUnknownNodes(t *testing.T) {
+	nodes := []*parser.Node{
+		{Type: parser.NodeKind(99)},
+		parser.NewListLiteral([]*parser.Node{{Type: parser.Identifier, Value: "a"}}),
+		{Type: parser.BinaryExpression, Value: "~", Left: &parser.Node{Type: parser.Identifier, Value: "a"},
+			Right: &parser.Node{Type: parser.Identifier, Value: "b"}},
+		{Type: parser.BinaryExpression, Value: ">", Right: &parser.Node{Type: parser.Identifier, Value: "b"},
+			Left: &parser.Node{Type: parser.AdditiveExpression, Value: "^", Left: &parser.Node{Type: parser.Identifier, Value: "a"},
+				Right: &parser.Node{Type: parser.Identifier, Value: "b"}}},
+	}
+
+	for _, node := range nodes {
+		if _, err := vm.Compile(node); err == nil {
+			t.Errorf("Expected an error compiling %+v", node)
+		}
+	}
+}
+
+// Test that evaluating rules without function calls, dates or list literals
+// outside IN does not allocate, even on attributes of mismatched types. Only
+// errors, which stop the evaluation, allocate their message.
+func TestProgramDoesNotAllocate(t *testing.T) {
+	rules := []string{
+		benchmarkRule,
+		"age >= 30 AND age <= 40 OR NOT active",
+		"(age + bonus) * 2 > 100 AND -age < 0 AND salary % 7 != 3",
+		"age IN [30, 31, 32] OR country NOT IN ['US', 'CA'] OR age IN [min_age, 40]",
+		"country IN countries OR tags CONTAINS 'vip'",
+		"user.address.country = 'US' AND orders[index].total > 100 AND orders[0].total > 100",
+		"name LIKE 'A%e' AND code MATCHES '^[A-Z][0-9]{3}$' AND name ENDS_WITH 'ce'",
+		"manager != null AND 'age' AND session > 1h",
+		"age = 30 OR department = 'Sales' OR active = true OR name != 'Al' OR age > 'abc' OR salary < 5 OR code = 1",
+		"country IN [1, true, 'US'] OR age IN ['30', min_age] OR age IN ages OR tags CONTAINS 'vip' OR manager = '3.5'",
+	}
+
+	mismatched := Context{
+		"age": "abc", "salary": "lots", "department": 7, "active": "yes", "country": 5, "name": 12,
+		"code": true, "tags": []interface{}{1, nil, true}, "ages": []interface{}{"30", false},
+		"user": map[string]interface{}{"address": "Paris"}, "orders": []interface{}{"first"},
+		"index": 0, "min_age": int64(31), "manager": 3.5, "bonus": "ten", "session": 90 * time.Minute,
+	}
+	contexts := []Context{benchmarkContexts[0], benchmarkContexts[1], differentialContexts[0], differentialContexts[1], mismatched}
+
+	for _, rule := range rules {
+		program := mustCompile(t, rule)
+		for _, context := range contexts {
+			if _, err := program.Run(context); err != nil {
+				continue
+			}
+			allocs := testing.AllocsPerRun(100, func() {
+				program.Run(context)
+			})
+			if allocs != 0 {
+				t.Errorf("Rule: %s\nContext: %v\nExpected no allocations, got %v", rule, context, allocs)
+			}
+		}
+	}
+}
+
+// Test the disassembly of a program
+func TestDisassemble(t *testing.T) {
+	program := mustCompile(t, "age > 30 AND (country IN ['US', min] OR lower(name) = 'al')")
+
+	expected := strings.Join([]string{
+		"0000 LOAD 0 ; age",
+		"0003 CONST 1 ; 30",
+		"0006 GREATER",
+		"0007 JUMP_IF_FALSE_OR_POP 48",
+		"0010 LOAD 2 ; country",
+		"0013 JUMP_IF_NIL_FALSE 29",
+		"0016 IN_SET 0 0 29 ; IN ['US', min]",
+		"0022 LOAD 3 ; min",
+		"0025 IN_LIST 1 0 ; IN",
+		"0029 JUMP_IF_TRUE_OR_POP 48",
+		"0032 LOAD 4 ; name",
+		"0035 ARGUMENT 0 0 44 ; argument 1 of lower",
+		"0041 CALL 0 ; lower",
+		"0044 CONST 5 ; \"al\"",
+		"0047 EQUAL",
+		"",
+	}, "\n")
+	if got := program.Disassemble(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func BenchmarkProgram(b *testing.B) {
+	program := mustCompile(b, benchmarkRule)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		program.Run(benchmarkContexts[i%len(benchmarkContexts)])
+	}
+}